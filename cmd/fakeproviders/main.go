@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/nutochk/ef-test/internal/fakeproviders"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type config struct {
+	Providers fakeproviders.Config
+	Log       logger.Config
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var cfg config
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		panic(err)
+	}
+	logger, err := logger.New(cfg.Log)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Providers.Port),
+		Handler:           fakeproviders.New(cfg.Providers, logger).Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("fake providers started", zap.String("addr", srv.Addr), zap.Uint64("seed", cfg.Providers.Seed))
+	if err = srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("fake providers stopped", zap.Error(err))
+	}
+}
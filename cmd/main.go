@@ -2,18 +2,31 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/nutochk/ef-test/docs"
+	"github.com/nutochk/ef-test/internal/auth"
 	"github.com/nutochk/ef-test/internal/config"
+	"github.com/nutochk/ef-test/internal/health"
+	"github.com/nutochk/ef-test/internal/leader"
+	"github.com/nutochk/ef-test/internal/metrics"
+	"github.com/nutochk/ef-test/internal/ratelimit"
 	"github.com/nutochk/ef-test/internal/repository"
+	"github.com/nutochk/ef-test/internal/scheduler"
 	"github.com/nutochk/ef-test/internal/server"
 	"github.com/nutochk/ef-test/internal/service"
+	"github.com/nutochk/ef-test/migrations"
+	"github.com/nutochk/ef-test/pkg/lifecycle"
 	"github.com/nutochk/ef-test/pkg/logger"
 	"github.com/nutochk/ef-test/pkg/postgres"
+	"github.com/nutochk/ef-test/pkg/tracing"
 	"go.uber.org/zap"
 )
 
@@ -24,36 +37,147 @@ func main() {
 	)
 	defer stop()
 
-	logger, err := logger.New()
+	configPath := flag.String("config", "", "path to YAML config file")
+	flag.Parse()
+
+	cfg, err := config.New(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if flag.Arg(0) == "migrate" {
+		if err = runMigrate(ctx, cfg, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	logger, err := logger.New(cfg.Log)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	cfg, err := config.New()
+	app := lifecycle.New(cfg.Lifecycle, logger)
+	app.AfterStop("logger", func(context.Context) error {
+		_ = logger.Sync()
+		return nil
+	})
+	logger.Info("effective config", zap.Any("config", cfg.Redacted()))
+	shutdownTracing, err := tracing.New(ctx, cfg.Tracing)
 	if err != nil {
-		logger.Fatal("failed to read config", zap.Error(err))
+		logger.Fatal("failed to init tracing", zap.Error(err))
 	}
-	logger.Debug("config content", zap.Any("config", cfg))
-	pgConn, err := postgres.New(cfg.Postgres)
+	pgPool, err := postgres.New(cfg.Postgres)
 	if err != nil {
 		logger.Fatal("failed to connect to postgres", zap.Error(err))
 	}
 	logger.Debug("connected to postgres successfully")
+	app.AfterStop("postgres", func(context.Context) error {
+		pgPool.Close()
+		return nil
+	})
+	migrator, err := postgres.NewMigrator(pgPool, migrations.FS)
+	if err != nil {
+		logger.Fatal("failed to load migrations", zap.Error(err))
+	}
+	app.AfterStop("migrator", func(context.Context) error { return migrator.Close() })
+	if !cfg.Postgres.SkipMigrations {
+		if _, err = migrator.Up(ctx); err != nil {
+			logger.Fatal("failed to apply migrations", zap.Error(err))
+		}
+	}
+	app.AfterStop("tracing", shutdownTracing)
+	if err = metrics.RegisterPool(pgPool); err != nil {
+		logger.Error("failed to register postgres pool metrics", zap.Error(err))
+	}
 
-	repo := repository.NewRepo(pgConn)
-
-	apiService := service.New(repo, *logger)
-	apiServer := server.New(apiService)
+	repo := repository.NewRepo(pgPool, *logger)
 
-	go func() {
-		logger.Info("Server is listening on port:" + strconv.Itoa(cfg.Port))
-		if err := apiServer.Run(cfg.Port); err != nil {
-			stop()
+	genderRules, err := service.LoadGenderRules(cfg.Enrichment.GenderRulesFile)
+	if err != nil {
+		logger.Fatal("failed to load gender rules", zap.Error(err))
+	}
+	dataset, err := service.LoadDataset(cfg.Enrichment.DatasetFile, cfg.Enrichment.DatasetReloadInterval, logger)
+	if err != nil {
+		logger.Fatal("failed to load enrichment dataset", zap.Error(err))
+	}
+	serviceOpts := []service.Option{service.WithGenderRules(genderRules)}
+	if dataset != nil {
+		logger.Info("enrichment dataset loaded", zap.String("path", cfg.Enrichment.DatasetFile), zap.Int("records", dataset.Len()))
+		serviceOpts = append(serviceOpts, service.WithDataset(dataset))
+	}
+	apiService := service.New(repo, *logger, cfg.Enrichment, serviceOpts...)
+	apiHealth := newHealth(cfg.Health, cfg.Enrichment, pgPool, migrator)
+	var serverOpts []server.Option
+	if cfg.Auth.Enabled {
+		keys := auth.NewAPIKeys(repo, cfg.Auth.BootstrapKeyHash)
+		authenticator, err := auth.New(cfg.Auth, keys)
+		if err != nil {
+			logger.Fatal("failed to init authentication", zap.Error(err))
+		}
+		permissions, err := auth.ParseRolePermissions(cfg.Auth.RolePermissions)
+		if err != nil {
+			logger.Fatal("failed to parse role permissions", zap.Error(err))
+		}
+		serverOpts = append(serverOpts, server.WithAuth(authenticator, auth.NewAuthorizer(permissions), keys))
+	}
+	if cfg.RateLimit.Enabled {
+		var limiter ratelimit.Limiter = ratelimit.NewMemory()
+		if cfg.RateLimit.Backend == ratelimit.BackendPostgres {
+			limiter = ratelimit.NewPostgres(pgPool)
 		}
-	}()
+		serverOpts = append(serverOpts, server.WithRateLimit(limiter,
+			ratelimit.Limit{Requests: cfg.RateLimit.ReadRequests, Window: cfg.RateLimit.Window},
+			ratelimit.Limit{Requests: cfg.RateLimit.WriteRequests, Window: cfg.RateLimit.Window},
+		))
+	}
+	apiServer := server.New(cfg.Server, apiService, apiHealth, logger, serverOpts...)
+
+	if cfg.Scheduler.Enabled {
+		elector := leader.New(cfg.Leader, pgPool, logger)
+		reenrichment := scheduler.New(cfg.Scheduler, repo, apiService, logger)
+		app.Go("reenrichment", func(ctx context.Context) error {
+			return elector.Run(ctx, "reenrichment", reenrichment.Run)
+		})
+	}
+
+	app.Serve("http", func() error {
+		logger.Info("Server is listening on " + apiServer.Addr())
+		return apiServer.Run()
+	}, apiServer.Shutdown)
+	app.BeforeStop("drain", func(context.Context) error {
+		logger.Info("Draining server...")
+		apiHealth.Drain()
+		time.Sleep(cfg.Health.DrainDelay)
+		return nil
+	})
 
-	<-ctx.Done()
-	logger.Info("Shutting down server...")
-	apiServer.Shutdown(ctx)
-	logger.Info("Server shut down")
-	defer pgConn.Close(context.Background())
+	if err = app.Run(ctx); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newHealth(cfg health.Config, enrichment service.Config, pool *pgxpool.Pool, migrator *postgres.Migrator) *health.Health {
+	checks := []health.Check{
+		{Name: "postgres", Run: pool.Ping, Timeout: cfg.CheckTimeout},
+		{Name: "migrations", Run: migrator.Check, Timeout: cfg.CheckTimeout},
+	}
+	if cfg.CheckProviders {
+		client := &http.Client{}
+		providers := []struct{ name, url string }{
+			{"agify", enrichment.AgifyURL},
+			{"genderize", enrichment.GenderizeURL},
+			{"nationalize", enrichment.NationalizeURL},
+		}
+		for _, p := range providers {
+			checks = append(checks, health.Check{
+				Name:     p.name,
+				Run:      health.HTTP(client, p.url),
+				Timeout:  cfg.CheckTimeout,
+				CacheTTL: cfg.ProviderCacheTTL,
+				Optional: true,
+			})
+		}
+	}
+	return health.New(checks...)
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nutochk/ef-test/internal/config"
+	"github.com/nutochk/ef-test/migrations"
+	"github.com/nutochk/ef-test/pkg/postgres"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = "usage: migrate up|down|status|redo|to <version>"
+
+// runMigrate executes migrate subcommand with arguments following it
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	pool, err := postgres.New(cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	m, err := postgres.NewMigrator(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		results, err := m.Up(ctx)
+		printResults(results...)
+		return err
+	case "down":
+		result, err := m.Down(ctx)
+		if result != nil {
+			printResults(result)
+		}
+		return err
+	case "redo":
+		results, err := m.Redo(ctx)
+		printResults(results...)
+		return err
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[1], err)
+		}
+		results, err := m.To(ctx, version)
+		printResults(results...)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tSOURCE")
+		for _, s := range statuses {
+			appliedAt := ""
+			if s.State == goose.StateApplied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, s.Source.Path)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func printResults(results ...*goose.MigrationResult) {
+	if len(results) == 0 {
+		fmt.Println("no migrations to apply")
+	}
+	for _, r := range results {
+		fmt.Println(r)
+	}
+}
@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/people": {
-            "get": {
-                "description": "Returns a list of people with the ability to filter and paginate",
+        "/admin/api-keys": {
+            "post": {
+                "description": "Issues a new api key. The key is returned only in this response, only its hash is stored",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,64 +25,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "people"
+                    "admin"
                 ],
-                "summary": "Get a list of people with filtering",
+                "summary": "create api key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Filter by name",
-                        "name": "name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by last name",
-                        "name": "surname",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Minimum age",
-                        "name": "age_min",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Maximum age",
-                        "name": "age_max",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Gender filter (male/female)",
-                        "name": "gender",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of entries per page",
-                        "name": "per_page",
-                        "in": "query"
+                        "description": "Key parameters",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateAPIKey"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/dto.PaginatedResponse"
+                            "$ref": "#/definitions/dto.CreatedAPIKey"
                         }
                     },
                     "400": {
-                        "description": "Incorrect filtering parameters",
+                        "description": "Incorrect data format",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -93,110 +58,89 @@ const docTemplate = `{
                     "500": {
                         "description": "Server error",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Creates a new record with data enrichment from external APIs",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "description": "Revokes an api key, requests with it are rejected afterwards",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "people"
+                    "admin"
                 ],
-                "summary": "create new record about person",
+                "summary": "revoke api key",
                 "parameters": [
                     {
-                        "description": "Personal data",
-                        "name": "person",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Person"
-                        }
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/dto.PersonInfo"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Incorrect data format",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Server error",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/people/{id}": {
+        "/admin/log-level": {
             "get": {
-                "description": "get the record of an existing person",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns current minimal level of log entries",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "people"
-                ],
-                "summary": "get by id record about person",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Person ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "get log level",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.PersonInfo"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Incorrect data format",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Server error",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/dto.LogLevel"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Updates the record of an existing person",
+                "description": "Changes minimal level of log entries at runtime (debug, info, warn, error)",
                 "consumes": [
                     "application/json"
                 ],
@@ -204,24 +148,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "people"
+                    "admin"
                 ],
-                "summary": "update record about person",
+                "summary": "set log level",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Person ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Personal data",
-                        "name": "person",
+                        "description": "Log level",
+                        "name": "level",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Person"
+                            "$ref": "#/definitions/dto.LogLevel"
                         }
                     }
                 ],
@@ -229,53 +166,70 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.PersonInfo"
+                            "$ref": "#/definitions/dto.LogLevel"
                         }
                     },
                     "400": {
-                        "description": "Incorrect data format",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not found",
+                        "description": "Incorrect level",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Server error",
-                        "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete the record of an existing person",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/enrich": {
+            "get": {
+                "description": "Determines age, gender and nationality like create does, without storing the person",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "people"
                 ],
-                "summary": "delete record about person",
+                "summary": "preview enrichment of person",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Person ID",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Name",
+                        "name": "name",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Surname",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patronymic",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 country to localize age and gender",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include answers of every enrichment provider",
+                        "name": "explain",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.EnrichPreview"
+                        }
                     },
                     "400": {
                         "description": "Incorrect data format",
@@ -283,10 +237,31 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "401": {
+                        "description": "Not authenticated",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -294,23 +269,819 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Enrichment providers rate limit exceeded",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.PaginatedResponse": {
-            "type": "object",
-            "properties": {
-                "data": {},
-                "pagination": {
-                    "type": "object",
-                    "properties": {
-                        "current_page": {
-                            "type": "integer"
-                        },
-                        "per_page": {
+        },
+        "/api/people": {
+            "get": {
+                "description": "Returns a list of people with the ability to filter and paginate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Get a list of people with filtering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by last name",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum age",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum age",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gender filter (male/female)",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only people with known (true) or unknown (false) age",
+                        "name": "age_known",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only people with known (true) or unknown (false) gender",
+                        "name": "gender_known",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only people with all attributes enriched at or after the time (RFC 3339)",
+                        "name": "enriched_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only people with some attribute enriched before the time or never (RFC 3339)",
+                        "name": "enriched_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of entries per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Incorrect filtering parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied or surname filter without pii scope",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a new record with data enrichment from external APIs, full_name is parsed into name, surname and patronymic",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "create new record about person",
+                "parameters": [
+                    {
+                        "description": "Personal data",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PersonInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Incorrect data format or ambiguous full_name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/people/stats": {
+            "get": {
+                "description": "Returns age histogram, gender distribution and top nationalities of people matching filters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Get aggregate statistics about people",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by last name",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum age",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum age",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gender filter (male/female)",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only people with known (true) or unknown (false) age",
+                        "name": "age_known",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only people with known (true) or unknown (false) gender",
+                        "name": "gender_known",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only people with all attributes enriched at or after the time (RFC 3339)",
+                        "name": "enriched_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only people with some attribute enriched before the time or never (RFC 3339)",
+                        "name": "enriched_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Width of age histogram bucket",
+                        "name": "bucket_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Number of top nationalities",
+                        "name": "top",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PeopleStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Incorrect filtering parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied or surname filter without pii scope",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/people/{id}": {
+            "get": {
+                "description": "get the record of an existing person",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "get by id record about person",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include answers of every enrichment provider",
+                        "name": "explain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PersonInfo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Incorrect data format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates the record of an existing person",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "update record about person",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Personal data",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PersonInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Incorrect data format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the record of an existing person",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "delete record about person",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Incorrect data format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Permission denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Reports that the process is alive",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Reports whether the service can receive traffic: database is reachable, migrations are applied and server is not draining",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.AgeBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.CreateAPIKey": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.CreatedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.EnrichPreview": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "contributions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Contribution"
+                    }
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "gender_probability": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Country"
+                    }
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "provenance": {
+                    "$ref": "#/definitions/models.Provenance"
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GenderStats": {
+            "type": "object",
+            "properties": {
+                "avg_probability": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "gender": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LogLevel": {
+            "type": "object",
+            "required": [
+                "level"
+            ],
+            "properties": {
+                "level": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.NationalityStats": {
+            "type": "object",
+            "properties": {
+                "avg_probability": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "country_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PaginatedResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "pagination": {
+                    "type": "object",
+                    "properties": {
+                        "current_page": {
+                            "type": "integer"
+                        },
+                        "per_page": {
                             "type": "integer"
                         },
                         "total": {
@@ -320,12 +1091,50 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.PeopleStats": {
+            "type": "object",
+            "properties": {
+                "age_avg": {
+                    "type": "number"
+                },
+                "age_histogram": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AgeBucket"
+                    }
+                },
+                "age_max": {
+                    "type": "integer"
+                },
+                "age_min": {
+                    "type": "integer"
+                },
+                "genders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GenderStats"
+                    }
+                },
+                "top_nationalities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.NationalityStats"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.PersonInfo": {
             "type": "object",
             "properties": {
                 "age": {
                     "type": "integer"
                 },
+                "created_at": {
+                    "type": "string"
+                },
                 "gender": {
                     "type": "string"
                 },
@@ -344,11 +1153,85 @@ const docTemplate = `{
                         "$ref": "#/definitions/models.Country"
                     }
                 },
+                "parsed_name": {
+                    "description": "ParsedName how full_name was split on create",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ParsedName"
+                        }
+                    ]
+                },
                 "patronymic": {
                     "type": "string"
                 },
+                "provenance": {
+                    "$ref": "#/definitions/models.Provenance"
+                },
                 "surname": {
                     "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.Result"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "cached": {
+                    "type": "boolean"
+                },
+                "duration_ms": {
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "optional": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Contribution": {
+            "type": "object",
+            "properties": {
+                "attribute": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "number"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "number"
                 }
             }
         },
@@ -363,9 +1246,43 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ParsedName": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "type": "number"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "order": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Person": {
             "type": "object",
             "properties": {
+                "country_hint": {
+                    "description": "CountryHint ISO 3166-1 alpha-2 country used to localize age and gender on create",
+                    "type": "string"
+                },
+                "full_name": {
+                    "description": "FullName is parsed into name, surname and patronymic on create, Locale selects its ordering conventions\nand is detected by script when empty",
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -383,6 +1300,16 @@ const docTemplate = `{
                 "age": {
                     "type": "integer"
                 },
+                "contributions": {
+                    "description": "Contributions answers of every provider, filled only when explanation is requested",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Contribution"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
                 "gender": {
                     "type": "string"
                 },
@@ -401,8 +1328,58 @@ const docTemplate = `{
                 "patronymic": {
                     "type": "string"
                 },
+                "provenance": {
+                    "$ref": "#/definitions/models.Provenance"
+                },
                 "surname": {
                     "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Provenance": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "$ref": "#/definitions/models.Source"
+                },
+                "country_hint": {
+                    "description": "CountryHint country given by client on create, kept even if localized requests fell back to global ones",
+                    "type": "string"
+                },
+                "gender": {
+                    "$ref": "#/definitions/models.Source"
+                },
+                "gender_conflict": {
+                    "description": "GenderConflict genderize and local rules disagreed about gender",
+                    "type": "boolean"
+                },
+                "localization": {
+                    "description": "Localization where the country of age and gender requests came from: hint, nationality or empty",
+                    "type": "string"
+                },
+                "nationality": {
+                    "$ref": "#/definitions/models.Source"
+                }
+            }
+        },
+        "models.Source": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "country": {
+                    "description": "Country the request to provider was localized with",
+                    "type": "string"
+                },
+                "enriched_at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/internal/repository"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	apiKeyPrefix = "pk_"
+)
+
+// KeyStore storage of hashed api keys
+type KeyStore interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+}
+
+// APIKeys issues, revokes and verifies api keys. Only sha256 hashes of keys are stored.
+type APIKeys struct {
+	store         KeyStore
+	bootstrapHash string
+}
+
+func NewAPIKeys(store KeyStore, bootstrapHash string) *APIKeys {
+	return &APIKeys{store: store, bootstrapHash: bootstrapHash}
+}
+
+// Create issues a new key, the plain key is returned only once. Key without roles gets reader role.
+func (a *APIKeys) Create(ctx context.Context, name string, roles, scopes []string) (string, *models.APIKey, error) {
+	if len(roles) == 0 {
+		roles = []string{RoleReader}
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	stored, err := a.store.CreateAPIKey(ctx, &models.APIKey{Name: name, Hash: HashAPIKey(key), Roles: roles, Scopes: scopes})
+	if err != nil {
+		return "", nil, err
+	}
+	return key, stored, nil
+}
+
+func (a *APIKeys) Revoke(ctx context.Context, id int) error {
+	return a.store.RevokeAPIKey(ctx, id)
+}
+
+func (a *APIKeys) Authenticate(ctx context.Context, r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	hash := HashAPIKey(key)
+	if a.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.bootstrapHash)) == 1 {
+		return &Principal{Subject: "bootstrap", Method: MethodAPIKey, Roles: []string{RoleAdmin}, Scopes: []string{ScopePII}}, nil
+	}
+
+	stored, err := a.store.GetAPIKeyByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotExist) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{
+		Subject: "api_key:" + strconv.Itoa(stored.Id),
+		Method:  MethodAPIKey,
+		Roles:   stored.Roles,
+		Scopes:  stored.Scopes,
+	}, nil
+}
+
+// HashAPIKey returns hex encoded sha256 of key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type Config struct {
+	Enabled          bool   `yaml:"AUTH_ENABLED" env:"AUTH_ENABLED" env-default:"false"`
+	BootstrapKeyHash string `yaml:"AUTH_BOOTSTRAP_KEY_HASH" env:"AUTH_BOOTSTRAP_KEY_HASH"`
+	JWTSecret        string `yaml:"AUTH_JWT_HMAC_SECRET" env:"AUTH_JWT_HMAC_SECRET"`
+	JWKSFile         string `yaml:"AUTH_JWT_JWKS_FILE" env:"AUTH_JWT_JWKS_FILE"`
+	JWTIssuer        string `yaml:"AUTH_JWT_ISSUER" env:"AUTH_JWT_ISSUER"`
+	JWTAudience      string `yaml:"AUTH_JWT_AUDIENCE" env:"AUTH_JWT_AUDIENCE"`
+	RolePermissions  string `yaml:"AUTH_ROLE_PERMISSIONS" env:"AUTH_ROLE_PERMISSIONS"`
+}
+
+// Principal authenticated caller
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Roles   []string `json:"roles,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
+
+// Authenticator identifies caller of the request.
+// It returns ErrNoCredentials if the request carries no credentials it understands.
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *http.Request) (*Principal, error)
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFromContext returns principal carried by ctx
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok
+}
+
+// Chain tries authenticators in order until one of them finds its credentials in the request
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, r *http.Request) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// New builds authenticator from api keys and, if configured, jwt
+func New(cfg Config, keys *APIKeys) (Authenticator, error) {
+	chain := Chain{keys}
+	j, err := NewJWT(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if j != nil {
+		chain = append(chain, j)
+	}
+	return chain, nil
+}
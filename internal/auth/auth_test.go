@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/internal/repository"
+)
+
+type memoryKeyStore struct {
+	keys []models.APIKey
+}
+
+func (m *memoryKeyStore) CreateAPIKey(_ context.Context, key *models.APIKey) (*models.APIKey, error) {
+	created := *key
+	created.Id = len(m.keys) + 1
+	m.keys = append(m.keys, created)
+	return &created, nil
+}
+
+func (m *memoryKeyStore) GetAPIKeyByHash(_ context.Context, hash string) (*models.APIKey, error) {
+	for _, k := range m.keys {
+		if k.Hash == hash {
+			return &k, nil
+		}
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (m *memoryKeyStore) RevokeAPIKey(_ context.Context, id int) error {
+	now := time.Now()
+	m.keys[id-1].RevokedAt = &now
+	return nil
+}
+
+func TestAPIKeys(t *testing.T) {
+	keys := NewAPIKeys(&memoryKeyStore{}, "")
+	key, stored, err := keys.Create(context.Background(), "dashboard", []string{RoleReader}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "/api/people", nil)
+	r.Header.Set(APIKeyHeader, key)
+	p, err := keys.Authenticate(context.Background(), r)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Method != MethodAPIKey || len(p.Roles) != 1 || p.Roles[0] != RoleReader {
+		t.Errorf("Unexpected principal %+v", p)
+	}
+
+	if err = keys.Revoke(context.Background(), stored.Id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err = keys.Authenticate(context.Background(), r); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected %v for revoked key, got %v", ErrInvalidCredentials, err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	a, err := New(Config{JWTSecret: "secret", JWTIssuer: "issuer"}, NewAPIKeys(&memoryKeyStore{}, ""))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sign := func(secret, issuer string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":   "alice",
+			"iss":   issuer,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "pii",
+		})
+		s, _ := token.SignedString([]byte(secret))
+		return s
+	}
+
+	r := httptest.NewRequest("GET", "/api/people", nil)
+	r.Header.Set("Authorization", "Bearer "+sign("secret", "issuer"))
+	p, err := a.Authenticate(context.Background(), r)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Subject != "alice" || len(p.Scopes) != 1 || p.Scopes[0] != "pii" {
+		t.Errorf("Unexpected principal %+v", p)
+	}
+
+	r.Header.Set("Authorization", "Bearer "+sign("other", "issuer"))
+	if _, err = a.Authenticate(context.Background(), r); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected %v for wrong secret, got %v", ErrInvalidCredentials, err)
+	}
+
+	r.Header.Del("Authorization")
+	if _, err = a.Authenticate(context.Background(), r); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected %v without credentials, got %v", ErrNoCredentials, err)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	permissions, err := ParseRolePermissions("reader=people:read; auditor = people:read , admin")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	a := NewAuthorizer(permissions)
+
+	reader := &Principal{Roles: []string{RoleReader}}
+	if !a.Allowed(reader, PermissionPeopleRead) || a.Allowed(reader, PermissionPeopleDelete) {
+		t.Errorf("Unexpected permissions of reader")
+	}
+	auditor := &Principal{Roles: []string{"auditor"}}
+	if !a.Allowed(auditor, PermissionAdmin) {
+		t.Errorf("Expected auditor to have %s permission", PermissionAdmin)
+	}
+	if a.IsRole(RoleEditor) {
+		t.Errorf("Expected %s to be unknown with custom mapping", RoleEditor)
+	}
+
+	if _, err = ParseRolePermissions("reader"); err == nil {
+		t.Errorf("Expected error for entry without permissions")
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWT verifies bearer tokens signed with HMAC secret or keys from local JWKS file
+type JWT struct {
+	keyfunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+	Scope string   `json:"scope"`
+}
+
+func NewJWT(cfg Config) (*JWT, error) {
+	var methods []string
+	var kf jwt.Keyfunc
+	switch {
+	case cfg.JWKSFile != "":
+		raw, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks file: %w", err)
+		}
+		jwks, err := keyfunc.NewJWKSetJSON(json.RawMessage(raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwks file: %w", err)
+		}
+		kf = jwks.Keyfunc
+		methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+	case cfg.JWTSecret != "":
+		secret := []byte(cfg.JWTSecret)
+		kf = func(*jwt.Token) (interface{}, error) { return secret, nil }
+		methods = []string{"HS256", "HS384", "HS512"}
+	default:
+		return nil, nil
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+	return &JWT{keyfunc: kf, parser: jwt.NewParser(opts...)}, nil
+}
+
+func (j *JWT) Authenticate(_ context.Context, r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	var c claims
+	if _, err := j.parser.ParseWithClaims(token, &c, j.keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	return &Principal{Subject: c.Subject, Method: MethodJWT, Roles: c.Roles, Scopes: strings.Fields(c.Scope)}, nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+const (
+	PermissionPeopleRead   = "people:read"
+	PermissionPeopleWrite  = "people:write"
+	PermissionPeopleDelete = "people:delete"
+	PermissionAdmin        = "admin"
+)
+
+// ScopePII grants access to surnames and patronymics, without it they are masked
+const ScopePII = "pii"
+
+// DefaultRolePermissions mapping used when AUTH_ROLE_PERMISSIONS is not set
+var DefaultRolePermissions = map[string][]string{
+	RoleReader: {PermissionPeopleRead},
+	RoleEditor: {PermissionPeopleRead, PermissionPeopleWrite},
+	RoleAdmin:  {PermissionPeopleRead, PermissionPeopleWrite, PermissionPeopleDelete, PermissionAdmin},
+}
+
+// Authorizer checks permissions of principals by their roles
+type Authorizer struct {
+	permissions map[string][]string
+}
+
+func NewAuthorizer(permissions map[string][]string) *Authorizer {
+	return &Authorizer{permissions: permissions}
+}
+
+// Allowed reports whether any role of p grants permission
+func (a *Authorizer) Allowed(p *Principal, permission string) bool {
+	for _, role := range p.Roles {
+		if slices.Contains(a.permissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRole reports whether role is known to the authorizer
+func (a *Authorizer) IsRole(role string) bool {
+	_, ok := a.permissions[role]
+	return ok
+}
+
+// ParseRolePermissions parses mapping in form "reader=people:read;editor=people:read,people:write"
+func ParseRolePermissions(s string) (map[string][]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultRolePermissions, nil
+	}
+	permissions := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		role, perms, ok := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("invalid role permissions entry: %q", entry)
+		}
+		permissions[role] = []string{}
+		for _, perm := range strings.Split(perms, ",") {
+			if perm = strings.TrimSpace(perm); perm != "" {
+				permissions[role] = append(permissions[role], perm)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
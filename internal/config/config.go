@@ -1,26 +1,204 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
+	"github.com/nutochk/ef-test/internal/auth"
+	"github.com/nutochk/ef-test/internal/health"
+	"github.com/nutochk/ef-test/internal/leader"
+	"github.com/nutochk/ef-test/internal/ratelimit"
+	"github.com/nutochk/ef-test/internal/scheduler"
+	"github.com/nutochk/ef-test/internal/server"
+	"github.com/nutochk/ef-test/internal/service"
+	"github.com/nutochk/ef-test/pkg/lifecycle"
+	"github.com/nutochk/ef-test/pkg/logger"
 	"github.com/nutochk/ef-test/pkg/postgres"
+	"github.com/nutochk/ef-test/pkg/tracing"
+	"go.uber.org/zap/zapcore"
 )
 
+const redacted = "[REDACTED]"
+
 type Config struct {
-	Port     int `yaml:"PORT" env:"PORT"`
-	Postgres postgres.Config
+	Server     server.Config    `yaml:"server"`
+	Postgres   postgres.Config  `yaml:"postgres"`
+	Enrichment service.Config   `yaml:"enrichment"`
+	Tracing    tracing.Config   `yaml:"tracing"`
+	Health     health.Config    `yaml:"health"`
+	Log        logger.Config    `yaml:"log"`
+	Auth       auth.Config      `yaml:"auth"`
+	RateLimit  ratelimit.Config `yaml:"rate_limit"`
+	Lifecycle  lifecycle.Config `yaml:"lifecycle"`
+	Scheduler  scheduler.Config `yaml:"scheduler"`
+	Leader     leader.Config    `yaml:"leader"`
 }
 
-func New() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		return nil, fmt.Errorf("failed to load .env file: %w", err)
+// New reads configuration in layers, every next layer overrides the previous one:
+// defaults, YAML file at path (if path is not empty), .env file, .env.local file (both if they exist),
+// environment variables.
+func New(path string) (*Config, error) {
+	// godotenv does not override variables which are already set, so the most specific file goes first
+	for _, file := range []string{".env.local", ".env"} {
+		if err := godotenv.Load(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load %s file: %w", file, err)
+		}
 	}
 	var cfg Config
-	if err := cleanenv.ReadEnv(&cfg); err != nil {
+	if path != "" {
+		if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+	} else if err := cleanenv.ReadEnv(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to load environment variables: %w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return &cfg, nil
 }
+
+// Validate checks ranges and formats of values
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "PORT must be in range 1-65535, got %d", c.Server.Port)
+	check(c.Server.ReadHeaderTimeout >= 0 && c.Server.ReadTimeout >= 0 && c.Server.WriteTimeout >= 0 && c.Server.IdleTimeout >= 0,
+		"SERVER_*_TIMEOUT must not be negative")
+	check(c.Server.RequestTimeout >= 0, "SERVER_REQUEST_TIMEOUT must not be negative, got %s", c.Server.RequestTimeout)
+	check(c.Server.WriteTimeout == 0 || c.Server.RequestTimeout > 0 && c.Server.RequestTimeout < c.Server.WriteTimeout,
+		"SERVER_REQUEST_TIMEOUT must be positive and shorter than SERVER_WRITE_TIMEOUT %s, got %s", c.Server.WriteTimeout, c.Server.RequestTimeout)
+	check(c.Server.MaxHeaderBytes >= 0, "SERVER_MAX_HEADER_BYTES must not be negative, got %d", c.Server.MaxHeaderBytes)
+	check(c.Server.MaxBodyBytes >= 0, "SERVER_MAX_BODY_BYTES must not be negative, got %d", c.Server.MaxBodyBytes)
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "SERVER_TRUSTED_PROXIES must contain IP addresses or CIDRs, got %q", proxy)
+	}
+	check(c.Postgres.Port > 0 && c.Postgres.Port <= 65535, "POSTGRES_PORT must be in range 1-65535, got %d", c.Postgres.Port)
+	check(c.Postgres.MaxConns >= 0, "POSTGRES_MAX_CONNS must not be negative, got %d", c.Postgres.MaxConns)
+
+	check(c.Enrichment.CacheTTL >= 0, "ENRICHMENT_CACHE_TTL must not be negative, got %s", c.Enrichment.CacheTTL)
+	check(c.Enrichment.CacheSize >= 0, "ENRICHMENT_CACHE_SIZE must not be negative, got %d", c.Enrichment.CacheSize)
+	for _, provider := range [][2]string{
+		{"ENRICHMENT_AGIFY_URL", c.Enrichment.AgifyURL},
+		{"ENRICHMENT_GENDERIZE_URL", c.Enrichment.GenderizeURL},
+		{"ENRICHMENT_NATIONALIZE_URL", c.Enrichment.NationalizeURL},
+	} {
+		check(isURL(provider[1]), "%s must be an http(s) URL, got %q", provider[0], provider[1])
+	}
+	check(c.Enrichment.Timeout > 0, "ENRICHMENT_TIMEOUT must be positive, got %s", c.Enrichment.Timeout)
+	switch c.Enrichment.Localization {
+	case service.LocalizationAuto, service.LocalizationHint, service.LocalizationOff:
+	default:
+		check(false, "ENRICHMENT_LOCALIZATION must be one of auto, hint, off, got %q", c.Enrichment.Localization)
+	}
+	if c.Enrichment.LocalGender {
+		check(len(c.Enrichment.GenderLanguages) > 0, "ENRICHMENT_GENDER_LANGUAGES must not be empty when ENRICHMENT_LOCAL_GENDER is enabled")
+	}
+	for _, attribute := range []struct {
+		name      string
+		providers []string
+	}{{"age", c.Enrichment.AgeProviders}, {"gender", c.Enrichment.GenderProviders}, {"nationality", c.Enrichment.NationalityProviders}} {
+		known := service.ProviderNames[attribute.name]
+		for _, name := range attribute.providers {
+			check(slices.Contains(known, name), "ENRICHMENT_%s_PROVIDERS: unknown provider %q, expected one of %s",
+				strings.ToUpper(attribute.name), name, strings.Join(known, ", "))
+		}
+	}
+	if slices.Contains(slices.Concat(c.Enrichment.AgeProviders, c.Enrichment.GenderProviders, c.Enrichment.NationalityProviders), "dataset") {
+		check(c.Enrichment.DatasetFile != "", "ENRICHMENT_DATASET_FILE is required when the dataset provider is selected")
+	}
+	_, ordersErr := service.ParseNameOrders(c.Enrichment.NameOrders)
+	check(ordersErr == nil, "ENRICHMENT_NAME_ORDERS: %v", ordersErr)
+	check(c.Enrichment.FullNameMinConfidence >= 0 && c.Enrichment.FullNameMinConfidence <= 1,
+		"ENRICHMENT_FULL_NAME_MIN_CONFIDENCE must be in range 0-1, got %v", c.Enrichment.FullNameMinConfidence)
+	check(c.Enrichment.DatasetReloadInterval >= 0, "ENRICHMENT_DATASET_RELOAD_INTERVAL must not be negative, got %s", c.Enrichment.DatasetReloadInterval)
+	for name, weight := range c.Enrichment.ProviderWeights {
+		check(weight >= 0, "ENRICHMENT_PROVIDER_WEIGHTS: weight of %q must not be negative, got %v", name, weight)
+	}
+	check(c.Enrichment.CountryThreshold >= 0 && c.Enrichment.CountryThreshold <= 1,
+		"ENRICHMENT_COUNTRY_THRESHOLD must be in range 0-1, got %v", c.Enrichment.CountryThreshold)
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		check(false, "TRACING_EXPORTER must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.OTLPEndpoint != "" {
+		check(isURL(c.Tracing.OTLPEndpoint), "TRACING_OTLP_ENDPOINT must be an http(s) URL, got %q", c.Tracing.OTLPEndpoint)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be in range 0-1, got %v", c.Tracing.SampleRatio)
+
+	check(c.Health.CheckTimeout > 0, "HEALTH_CHECK_TIMEOUT must be positive, got %s", c.Health.CheckTimeout)
+	check(c.Health.DrainDelay >= 0, "HEALTH_DRAIN_DELAY must not be negative, got %s", c.Health.DrainDelay)
+
+	_, err := zapcore.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL must be one of debug, info, warn, error, got %q", c.Log.Level)
+	check(c.Log.Format == logger.FormatJSON || c.Log.Format == logger.FormatConsole, "LOG_FORMAT must be json or console, got %q", c.Log.Format)
+	check(c.Log.SamplingInitial >= 0 && c.Log.SamplingThereafter >= 0, "LOG_SAMPLING_* must not be negative")
+
+	if c.Auth.Enabled {
+		check(c.Auth.BootstrapKeyHash == "" || len(c.Auth.BootstrapKeyHash) == 64, "AUTH_BOOTSTRAP_KEY_HASH must be a hex encoded sha256")
+		check(c.Auth.JWTSecret == "" || len(c.Auth.JWTSecret) >= 32, "AUTH_JWT_HMAC_SECRET must be at least 32 bytes long")
+		_, err = auth.ParseRolePermissions(c.Auth.RolePermissions)
+		check(err == nil, "AUTH_ROLE_PERMISSIONS: %v", err)
+	}
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.Backend == ratelimit.BackendMemory || c.RateLimit.Backend == ratelimit.BackendPostgres,
+			"RATE_LIMIT_BACKEND must be memory or postgres, got %q", c.RateLimit.Backend)
+		check(c.RateLimit.Window >= time.Second, "RATE_LIMIT_WINDOW must be at least 1s, got %s", c.RateLimit.Window)
+		check(c.RateLimit.ReadRequests > 0 && c.RateLimit.WriteRequests > 0, "RATE_LIMIT_*_REQUESTS must be positive")
+	}
+
+	if c.Scheduler.Enabled {
+		check(c.Scheduler.Interval > 0, "SCHEDULER_INTERVAL must be positive, got %s", c.Scheduler.Interval)
+		check(c.Scheduler.MaxAge > 0, "SCHEDULER_MAX_AGE must be positive, got %s", c.Scheduler.MaxAge)
+		check(c.Scheduler.MinProbability >= 0 && c.Scheduler.MinProbability <= 1,
+			"SCHEDULER_MIN_PROBABILITY must be in range 0-1, got %v", c.Scheduler.MinProbability)
+		check(c.Scheduler.MinRecheck >= 0, "SCHEDULER_MIN_RECHECK must not be negative, got %s", c.Scheduler.MinRecheck)
+		check(c.Scheduler.BatchSize > 0, "SCHEDULER_BATCH_SIZE must be positive, got %d", c.Scheduler.BatchSize)
+		check(c.Scheduler.Lease > 0, "SCHEDULER_LEASE must be positive, got %s", c.Scheduler.Lease)
+		check(c.Scheduler.RatePerMinute >= 0, "SCHEDULER_RATE_PER_MINUTE must not be negative, got %d", c.Scheduler.RatePerMinute)
+		check(c.Leader.RenewInterval > 0 && c.Leader.LeaseTimeout > 0 && c.Leader.RetryInterval > 0, "LEADER_* intervals must be positive")
+		check(c.Leader.LeaseTimeout <= c.Leader.RenewInterval, "LEADER_LEASE_TIMEOUT must not exceed LEADER_RENEW_INTERVAL")
+	}
+
+	check(c.Lifecycle.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive, got %s", c.Lifecycle.ShutdownTimeout)
+	check(c.Lifecycle.HooksTimeout > 0, "SHUTDOWN_HOOKS_TIMEOUT must be positive, got %s", c.Lifecycle.HooksTimeout)
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of config with secrets replaced, safe to be logged
+func (c Config) Redacted() Config {
+	if c.Postgres.Password != "" {
+		c.Postgres.Password = redacted
+	}
+	if c.Auth.JWTSecret != "" {
+		c.Auth.JWTSecret = redacted
+	}
+	if c.Auth.BootstrapKeyHash != "" {
+		c.Auth.BootstrapKeyHash = redacted
+	}
+	return c
+}
+
+func isURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
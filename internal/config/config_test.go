@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLayers(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	path := filepath.Join(dir, "config.yaml")
+	yaml := "server:\n  PORT: 9000\npostgres:\n  POSTGRES_HOST: db\n  POSTGRES_USER: user\n  POSTGRES_DB: people\n  POSTGRES_PASSWORD: secret\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POSTGRES_HOST", "override")
+
+	cfg, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 9000 {
+		t.Errorf("port from file: got %d", cfg.Server.Port)
+	}
+	if cfg.Postgres.Host != "override" {
+		t.Errorf("env must override file: got %q", cfg.Postgres.Host)
+	}
+	if cfg.Postgres.Port != 5432 || cfg.Log.Level != "info" {
+		t.Errorf("defaults not applied: %d %q", cfg.Postgres.Port, cfg.Log.Level)
+	}
+	if r := cfg.Redacted(); r.Postgres.Password != redacted || cfg.Postgres.Password != "secret" {
+		t.Errorf("password must be redacted only in copy")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := "server:\n  PORT: 70000\npostgres:\n  POSTGRES_HOST: db\n  POSTGRES_USER: user\n  POSTGRES_DB: people\n" +
+		"enrichment:\n  ENRICHMENT_GENDER_PROVIDERS: [genderize, agify]\n" +
+		"tracing:\n  TRACING_SAMPLE_RATIO: 2\n  TRACING_OTLP_ENDPOINT: localhost:4318\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"PORT", "ENRICHMENT_GENDER_PROVIDERS", "TRACING_SAMPLE_RATIO", "TRACING_OTLP_ENDPOINT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// LogLevel minimal level of log entries
+type LogLevel struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// CreateAPIKey parameters of a new api key
+type CreateAPIKey struct {
+	Name   string   `json:"name" binding:"required"`
+	Roles  []string `json:"roles"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreatedAPIKey issued api key, the key is shown only once
+type CreatedAPIKey struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	Roles     []string  `json:"roles"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
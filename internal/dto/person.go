@@ -1,17 +1,26 @@
 package dto
 
-import "github.com/nutochk/ef-test/internal/models"
+import (
+	"time"
 
-// PersonInfo information about person with id
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+// PersonInfo information about person with id, unknown age and gender are null
 type PersonInfo struct {
-	Id                int              `json:"id"`
-	Name              string           `json:"name"`
-	Surname           string           `json:"surname"`
-	Patronymic        string           `json:"patronymic"`
-	Age               int              `json:"age"`
-	Gender            string           `json:"gender"`
-	GenderProbability float64          `json:"gender_probability"`
-	Nationality       []models.Country `json:"nationality"`
+	Id                int               `json:"id"`
+	Name              string            `json:"name"`
+	Surname           string            `json:"surname"`
+	Patronymic        string            `json:"patronymic"`
+	Age               *int              `json:"age"`
+	Gender            *string           `json:"gender"`
+	GenderProbability *float64          `json:"gender_probability"`
+	Nationality       []models.Country  `json:"nationality"`
+	Provenance        models.Provenance `json:"provenance"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	// ParsedName how full_name was split on create
+	ParsedName *models.ParsedName `json:"parsed_name,omitempty"`
 }
 
 type PersonFilter struct {
@@ -20,6 +29,36 @@ type PersonFilter struct {
 	AgeMin  int    `form:"age_min"`
 	AgeMax  int    `form:"age_max"`
 	Gender  string `form:"gender"`
+	// AgeKnown keeps people with known (true) or unknown (false) age
+	AgeKnown *bool `form:"age_known"`
+	// GenderKnown keeps people with known (true) or unknown (false) gender
+	GenderKnown *bool `form:"gender_known"`
+	// EnrichedAfter keeps people whose every attribute was enriched at or after the time
+	EnrichedAfter time.Time `form:"enriched_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	// EnrichedBefore keeps people with at least one attribute enriched before the time or never
+	EnrichedBefore time.Time `form:"enriched_before" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// EnrichQuery person to preview enrichment for, Country localizes age and gender like country_hint on create
+type EnrichQuery struct {
+	Name       string `form:"name" binding:"required"`
+	Surname    string `form:"surname"`
+	Patronymic string `form:"patronymic"`
+	Country    string `form:"country" binding:"omitempty,iso3166_1_alpha2"`
+	Explain    bool   `form:"explain"`
+}
+
+// EnrichPreview result of enrichment which is not stored
+type EnrichPreview struct {
+	Name              string                `json:"name"`
+	Surname           string                `json:"surname"`
+	Patronymic        string                `json:"patronymic"`
+	Age               *int                  `json:"age"`
+	Gender            *string               `json:"gender"`
+	GenderProbability *float64              `json:"gender_probability"`
+	Nationality       []models.Country      `json:"nationality"`
+	Provenance        models.Provenance     `json:"provenance"`
+	Contributions     []models.Contribution `json:"contributions,omitempty"`
 }
 
 type Pagination struct {
@@ -0,0 +1,39 @@
+package dto
+
+// StatsQuery parameters of aggregate statistics
+type StatsQuery struct {
+	BucketSize int `form:"bucket_size"`
+	Top        int `form:"top"`
+}
+
+// PeopleStats aggregate statistics about people
+type PeopleStats struct {
+	Total            int                `json:"total"`
+	AgeMin           int                `json:"age_min"`
+	AgeMax           int                `json:"age_max"`
+	AgeAvg           float64            `json:"age_avg"`
+	AgeHistogram     []AgeBucket        `json:"age_histogram"`
+	Genders          []GenderStats      `json:"genders"`
+	TopNationalities []NationalityStats `json:"top_nationalities"`
+}
+
+// AgeBucket number of people with age in [from, to]
+type AgeBucket struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+// GenderStats number of people with gender and average probability of it
+type GenderStats struct {
+	Gender         string  `json:"gender"`
+	Count          int     `json:"count"`
+	AvgProbability float64 `json:"avg_probability"`
+}
+
+// NationalityStats number of people whose most probable nationality is country
+type NationalityStats struct {
+	CountryId      string  `json:"country_id"`
+	Count          int     `json:"count"`
+	AvgProbability float64 `json:"avg_probability"`
+}
@@ -0,0 +1,213 @@
+// Package fakeproviders serves agify, genderize and nationalize compatible APIs with generated answers
+// for local development and tests
+package fakeproviders
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	Agify       = "agify"
+	Genderize   = "genderize"
+	Nationalize = "nationalize"
+)
+
+// countries nationalities are drawn from
+var countries = []string{"RU", "UA", "BY", "KZ", "PL", "US", "GB", "DE", "FR", "IT", "ES", "TR", "CN", "IN", "BR"}
+
+// Config of fake providers, answers depend only on Seed, provider and query, faults are drawn from
+// a sequence seeded by Seed too
+type Config struct {
+	Port int    `yaml:"FAKE_PROVIDERS_PORT" env:"FAKE_PROVIDERS_PORT" env-default:"8085"`
+	Seed uint64 `yaml:"FAKE_PROVIDERS_SEED" env:"FAKE_PROVIDERS_SEED" env-default:"1"`
+	// Latency of every response, increased by random value up to Jitter
+	Latency time.Duration `yaml:"FAKE_PROVIDERS_LATENCY" env:"FAKE_PROVIDERS_LATENCY"`
+	Jitter  time.Duration `yaml:"FAKE_PROVIDERS_JITTER" env:"FAKE_PROVIDERS_JITTER"`
+	// ErrorRate and RateLimitRate fractions of requests answered with 500 and 429
+	ErrorRate     float64 `yaml:"FAKE_PROVIDERS_ERROR_RATE" env:"FAKE_PROVIDERS_ERROR_RATE"`
+	RateLimitRate float64 `yaml:"FAKE_PROVIDERS_RATE_LIMIT_RATE" env:"FAKE_PROVIDERS_RATE_LIMIT_RATE"`
+	// UnknownRate fraction of names the providers do not know
+	UnknownRate float64 `yaml:"FAKE_PROVIDERS_UNKNOWN_RATE" env:"FAKE_PROVIDERS_UNKNOWN_RATE" env-default:"0.05"`
+	// MaxBatch maximal number of name[] parameters in a request
+	MaxBatch int `yaml:"FAKE_PROVIDERS_MAX_BATCH" env:"FAKE_PROVIDERS_MAX_BATCH" env-default:"10"`
+}
+
+type AgeResponse struct {
+	Count     int    `json:"count"`
+	Name      string `json:"name"`
+	Age       *int   `json:"age"`
+	CountryId string `json:"country_id,omitempty"`
+}
+
+type GenderResponse struct {
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+	CountryId   string  `json:"country_id,omitempty"`
+}
+
+type Country struct {
+	CountryId   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+type NationalityResponse struct {
+	Count   int       `json:"count"`
+	Name    string    `json:"name"`
+	Country []Country `json:"country"`
+}
+
+type Server struct {
+	cfg    Config
+	logger *logger.Logger
+
+	mu     sync.Mutex
+	faults *rand.Rand
+}
+
+func New(cfg Config, log *logger.Logger) *Server {
+	return &Server{cfg: cfg, logger: log, faults: rand.New(rand.NewPCG(cfg.Seed, 0))}
+}
+
+// Handler serves providers under /agify/, /genderize/ and /nationalize/, so their base URLs are
+// http://host:port/agify and so on
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for provider, answer := range map[string]func(name, country string) any{
+		Agify:       func(name, country string) any { return s.Age(name, country) },
+		Genderize:   func(name, country string) any { return s.Gender(name, country) },
+		Nationalize: func(name, _ string) any { return s.Nationality(name) },
+	} {
+		mux.HandleFunc("/"+provider, s.handle(provider, answer))
+		mux.HandleFunc("/"+provider+"/", s.handle(provider, answer))
+	}
+	return mux
+}
+
+func (s *Server) handle(provider string, answer func(name, country string) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		names, batch := query["name[]"], true
+		if len(names) == 0 {
+			names, batch = query["name"], false
+		}
+		delay, status := s.fault()
+		time.Sleep(delay)
+		log := s.logger.With(zap.String("provider", provider), zap.Strings("names", names), zap.Int("status", status))
+
+		w.Header().Set("X-Rate-Limit-Limit", "1000")
+		w.Header().Set("X-Rate-Limit-Reset", strconv.Itoa(int(time.Until(time.Now().Truncate(24*time.Hour).Add(24*time.Hour)).Seconds())))
+		switch {
+		case status == http.StatusTooManyRequests:
+			w.Header().Set("X-Rate-Limit-Remaining", "0")
+			writeJSON(w, status, map[string]string{"error": "Request limit reached"})
+		case status != http.StatusOK:
+			writeJSON(w, status, map[string]string{"error": "Internal server error"})
+		case len(names) == 0 || slices.Contains(names, ""):
+			status = http.StatusUnprocessableEntity
+			writeJSON(w, status, map[string]string{"error": "Missing 'name' parameter"})
+		case batch && len(names) > s.cfg.MaxBatch:
+			status = http.StatusUnprocessableEntity
+			writeJSON(w, status, map[string]string{"error": "Invalid 'name[]' parameter"})
+		case batch:
+			w.Header().Set("X-Rate-Limit-Remaining", "999")
+			answers := make([]any, len(names))
+			for i, name := range names {
+				answers[i] = answer(name, query.Get("country_id"))
+			}
+			writeJSON(w, status, answers)
+		default:
+			w.Header().Set("X-Rate-Limit-Remaining", "999")
+			writeJSON(w, status, answer(names[0], query.Get("country_id")))
+		}
+		log.Debug("fake provider request served", zap.Duration("delay", delay))
+	}
+}
+
+// fault draws latency and status of the next response
+func (s *Server) fault() (time.Duration, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delay := s.cfg.Latency
+	if s.cfg.Jitter > 0 {
+		delay += time.Duration(s.faults.Int64N(int64(s.cfg.Jitter)))
+	}
+	roll := s.faults.Float64()
+	switch {
+	case roll < s.cfg.RateLimitRate:
+		return delay, http.StatusTooManyRequests
+	case roll < s.cfg.RateLimitRate+s.cfg.ErrorRate:
+		return delay, http.StatusInternalServerError
+	}
+	return delay, http.StatusOK
+}
+
+// random returns generator determined by seed and query, so the same query always gets the same answer
+func (s *Server) random(provider, name, country string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(provider + "|" + strings.ToLower(name) + "|" + strings.ToUpper(country)))
+	return rand.New(rand.NewPCG(s.cfg.Seed, h.Sum64()))
+}
+
+func (s *Server) Age(name, country string) AgeResponse {
+	r := s.random(Agify, name, country)
+	resp := AgeResponse{Name: name, CountryId: country}
+	if r.Float64() < s.cfg.UnknownRate {
+		return resp
+	}
+	age := 18 + r.IntN(63)
+	resp.Age, resp.Count = &age, 1+r.IntN(50000)
+	return resp
+}
+
+func (s *Server) Gender(name, country string) GenderResponse {
+	r := s.random(Genderize, name, country)
+	resp := GenderResponse{Name: name, CountryId: country}
+	if r.Float64() < s.cfg.UnknownRate {
+		return resp
+	}
+	gender := "male"
+	if r.IntN(2) == 1 {
+		gender = "female"
+	}
+	resp.Gender, resp.Count = &gender, 1+r.IntN(50000)
+	resp.Probability = math.Round((0.5+r.Float64()/2)*100) / 100
+	return resp
+}
+
+func (s *Server) Nationality(name string) NationalityResponse {
+	r := s.random(Nationalize, name, "")
+	resp := NationalityResponse{Name: name, Country: []Country{}}
+	if r.Float64() < s.cfg.UnknownRate {
+		return resp
+	}
+	resp.Count = 1 + r.IntN(50000)
+	left := 1.0
+	for _, i := range r.Perm(len(countries))[:1+r.IntN(5)] {
+		p := math.Round(left*r.Float64()*1000) / 1000
+		left -= p
+		resp.Country = append(resp.Country, Country{CountryId: countries[i], Probability: p})
+	}
+	sort.Slice(resp.Country, func(i, j int) bool { return resp.Country[i].Probability > resp.Country[j].Probability })
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
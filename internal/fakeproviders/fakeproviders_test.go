@@ -0,0 +1,68 @@
+package fakeproviders
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nutochk/ef-test/pkg/logger"
+)
+
+func newServer(t *testing.T, cfg Config) *httptest.Server {
+	log, _ := logger.New(logger.Config{Level: "error"})
+	srv := httptest.NewServer(New(cfg, log).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, url string, v any) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestDeterministic(t *testing.T) {
+	first := New(Config{Seed: 7}, nil)
+	second := New(Config{Seed: 7}, nil)
+	a, b := first.Gender("Olena", "UA"), second.Gender("olena", "ua")
+	if a.Gender == nil || b.Gender == nil || *a.Gender != *b.Gender || a.Probability != b.Probability || a.Count != b.Count {
+		t.Errorf("same seed and query must give the same answer, got %+v and %+v", a, b)
+	}
+	if first.Nationality("Olena").Count == New(Config{Seed: 8}, nil).Nationality("Olena").Count {
+		t.Error("another seed is expected to give another answer")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	srv := newServer(t, Config{Seed: 1, MaxBatch: 2})
+	var ages []AgeResponse
+	if status := get(t, srv.URL+"/agify?name[]=Ivan&name[]=Olena", &ages); status != http.StatusOK || len(ages) != 2 || ages[1].Name != "Olena" {
+		t.Errorf("unexpected batch response %d %+v", status, ages)
+	}
+	if status := get(t, srv.URL+"/agify?name[]=a&name[]=b&name[]=c", nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for too large batch, got %d", status)
+	}
+	if status := get(t, srv.URL+"/agify/", nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 without name, got %d", status)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	srv := newServer(t, Config{Seed: 1, RateLimitRate: 1})
+	if status := get(t, srv.URL+"/genderize/?name=Ivan", nil); status != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", status)
+	}
+	srv = newServer(t, Config{Seed: 1, ErrorRate: 1})
+	if status := get(t, srv.URL+"/nationalize/?name=Ivan", nil); status != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", status)
+	}
+}
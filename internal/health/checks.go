@@ -0,0 +1,26 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// HTTP checks that url responds without server error
+func HTTP(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected response status: %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+var ErrDraining = errors.New("server is draining")
+
+// Check dependency of the service verified by readiness probe
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Timeout limits a single run of the check
+	Timeout time.Duration
+	// CacheTTL reuses the last result for this period instead of running the check on every probe
+	CacheTTL time.Duration
+	// Optional checks are reported but do not make the service unready
+	Optional bool
+}
+
+// Result state of one check
+type Result struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+	Optional   bool    `json:"optional,omitempty"`
+	Cached     bool    `json:"cached,omitempty"`
+}
+
+// Report state of the service
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type Health struct {
+	checks   []*check
+	draining atomic.Bool
+}
+
+type check struct {
+	Check
+	mu      sync.Mutex
+	last    Result
+	checked time.Time
+}
+
+func New(checks ...Check) *Health {
+	h := &Health{}
+	for _, c := range checks {
+		h.checks = append(h.checks, &check{Check: c})
+	}
+	return h
+}
+
+// Drain makes readiness fail so that traffic is moved away before shutdown
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is able to serve requests
+func (h *Health) Live() Report {
+	return Report{Status: StatusUp}
+}
+
+// Ready runs all checks concurrently and reports whether the service may receive traffic
+func (h *Health) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(h.checks)+1)}
+	if h.draining.Load() {
+		report.Status = StatusDown
+		report.Checks["drain"] = Result{Status: StatusDown, Error: ErrDraining.Error()}
+	}
+
+	results := make([]Result, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.result(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range h.checks {
+		report.Checks[c.Name] = results[i]
+		if results[i].Status != StatusUp && !c.Optional {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (c *check) result(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.CacheTTL > 0 && !c.checked.IsZero() && time.Since(c.checked) < c.CacheTTL {
+		r := c.last
+		r.Cached = true
+		return r
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	err := c.Run(ctx)
+	r := Result{
+		Status:     StatusUp,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Optional:   c.Optional,
+	}
+	if err != nil {
+		r.Status = StatusDown
+		r.Error = err.Error()
+	}
+	c.last = r
+	c.checked = time.Now()
+	return r
+}
+
+type Config struct {
+	CheckTimeout     time.Duration `yaml:"HEALTH_CHECK_TIMEOUT" env:"HEALTH_CHECK_TIMEOUT" env-default:"2s"`
+	CheckProviders   bool          `yaml:"HEALTH_CHECK_PROVIDERS" env:"HEALTH_CHECK_PROVIDERS" env-default:"false"`
+	ProviderCacheTTL time.Duration `yaml:"HEALTH_PROVIDER_CACHE_TTL" env:"HEALTH_PROVIDER_CACHE_TTL" env-default:"1m"`
+	DrainDelay       time.Duration `yaml:"HEALTH_DRAIN_DELAY" env:"HEALTH_DRAIN_DELAY" env-default:"5s"`
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	calls := 0
+	h := New(
+		Check{Name: "db", Run: func(ctx context.Context) error { return nil }},
+		Check{Name: "provider", Run: func(ctx context.Context) error {
+			calls++
+			return errors.New("unreachable")
+		}, CacheTTL: time.Minute, Optional: true},
+	)
+
+	report := h.Ready(context.Background())
+	if report.Status != StatusUp {
+		t.Errorf("Expected status %s, got %s", StatusUp, report.Status)
+	}
+	if report.Checks["provider"].Status != StatusDown {
+		t.Errorf("Expected provider status %s, got %s", StatusDown, report.Checks["provider"].Status)
+	}
+
+	report = h.Ready(context.Background())
+	if !report.Checks["provider"].Cached || calls != 1 {
+		t.Errorf("Expected cached provider result, got %d calls", calls)
+	}
+}
+
+func TestReadyDraining(t *testing.T) {
+	h := New(Check{Name: "db", Run: func(ctx context.Context) error { return nil }})
+	h.Drain()
+
+	report := h.Ready(context.Background())
+	if report.Status != StatusDown {
+		t.Errorf("Expected status %s, got %s", StatusDown, report.Status)
+	}
+	if h.Live().Status != StatusUp {
+		t.Errorf("Expected live status %s while draining", StatusUp)
+	}
+}
+
+func TestReadyFailedCheck(t *testing.T) {
+	h := New(Check{Name: "db", Run: func(ctx context.Context) error { return errors.New("connection refused") }})
+
+	report := h.Ready(context.Background())
+	if report.Status != StatusDown {
+		t.Errorf("Expected status %s, got %s", StatusDown, report.Status)
+	}
+	if report.Checks["db"].Error != "connection refused" {
+		t.Errorf("Expected error of check, got %q", report.Checks["db"].Error)
+	}
+}
@@ -0,0 +1,138 @@
+// Package leader elects a single instance to run a named background job using postgres advisory locks
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nutochk/ef-test/internal/metrics"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const keyPrefix = "people-api:"
+
+type Config struct {
+	RenewInterval time.Duration `yaml:"LEADER_RENEW_INTERVAL" env:"LEADER_RENEW_INTERVAL" env-default:"5s"`
+	LeaseTimeout  time.Duration `yaml:"LEADER_LEASE_TIMEOUT" env:"LEADER_LEASE_TIMEOUT" env-default:"3s"`
+	RetryInterval time.Duration `yaml:"LEADER_RETRY_INTERVAL" env:"LEADER_RETRY_INTERVAL" env-default:"10s"`
+}
+
+var errLost = errors.New("leadership lost")
+
+// Elector campaigns for leadership of jobs. The advisory lock is held by a session
+// of a connection taken out of the pool, so it is released by postgres when the instance dies.
+type Elector struct {
+	cfg    Config
+	pool   *pgxpool.Pool
+	logger *logger.Logger
+}
+
+func New(cfg Config, pool *pgxpool.Pool, log *logger.Logger) *Elector {
+	return &Elector{cfg: cfg, pool: pool, logger: log}
+}
+
+// Run executes job while this instance is the leader of name. When leadership is lost the job context
+// is cancelled and the instance campaigns again, Run returns when ctx is cancelled.
+func (e *Elector) Run(ctx context.Context, name string, job func(ctx context.Context) error) error {
+	log := e.logger.With(zap.String("job", name))
+	key := lockKey(name)
+	for {
+		err := e.lead(ctx, name, key, log, job)
+		if err != nil && ctx.Err() == nil {
+			log.Warn("leadership campaign failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.cfg.RetryInterval):
+		}
+	}
+}
+
+// lead tries to become the leader once and runs job until it returns, ctx is cancelled or the lock is lost
+func (e *Elector) lead(ctx context.Context, name string, key int64, log *logger.Logger, job func(ctx context.Context) error) error {
+	pooled, err := e.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// the session owns the lock, closing the connection releases it
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	var acquired bool
+	if err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+	if !acquired {
+		log.Debug("leadership is held by another instance")
+		return nil
+	}
+
+	metrics.Leader.WithLabelValues(name).Set(1)
+	metrics.LeaderChanges.WithLabelValues(name, "acquired").Inc()
+	log.Info("leadership acquired")
+	defer metrics.Leader.WithLabelValues(name).Set(0)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- job(jobCtx) }()
+
+	ticker := time.NewTicker(e.cfg.RenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err = <-done:
+			metrics.LeaderChanges.WithLabelValues(name, "released").Inc()
+			if ctx.Err() != nil {
+				log.Info("leadership released on shutdown")
+				return nil
+			}
+			log.Warn("job stopped, leadership released", zap.Error(err))
+			return nil
+		case <-ticker.C:
+			if err = e.renew(ctx, conn, key); err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				cancel()
+				<-done
+				metrics.LeaderChanges.WithLabelValues(name, "lost").Inc()
+				log.Error("leadership lost, job cancelled", zap.Error(err))
+				return nil
+			}
+		}
+	}
+}
+
+// renew checks within LeaseTimeout that the session is alive and still holds the lock
+func (e *Elector) renew(ctx context.Context, conn *pgx.Conn, key int64) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.LeaseTimeout)
+	defer cancel()
+	var held bool
+	err := conn.QueryRow(ctx, `SELECT EXISTS (
+		SELECT 1 FROM pg_locks
+		WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted
+			AND objsubid = 1 AND ((classid::bigint << 32) | objid::bigint) = $1
+	)`, key).Scan(&held)
+	if err != nil {
+		return fmt.Errorf("failed to check advisory lock: %w", err)
+	}
+	if !held {
+		return errLost
+	}
+	return nil
+}
+
+// lockKey maps job name to advisory lock key
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(keyPrefix + name))
+	return int64(h.Sum64())
+}
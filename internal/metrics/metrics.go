@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "people_api"
+
+var (
+	// HTTPRequests number of handled requests by method, route template and status code
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of handled HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration latency of handled requests by method, route template and status code
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of handled HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// ProviderDuration latency of calls to enrichment providers
+	ProviderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "enrichment",
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of requests to enrichment providers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	// ProviderErrors number of failed calls to enrichment providers
+	ProviderErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "enrichment",
+		Name:      "provider_errors_total",
+		Help:      "Number of failed requests to enrichment providers.",
+	}, []string{"provider"})
+
+	// CacheRequests number of enrichment cache lookups by result (hit or miss)
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "enrichment",
+		Name:      "cache_requests_total",
+		Help:      "Number of enrichment cache lookups by result.",
+	}, []string{"provider", "result"})
+
+	// Reenrichments number of people processed by re-enrichment by result (changed, unchanged, failed)
+	Reenrichments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reenrichment",
+		Name:      "people_total",
+		Help:      "Number of people processed by re-enrichment by result.",
+	}, []string{"result"})
+
+	// ReenrichmentChanges number of attribute values changed by re-enrichment
+	ReenrichmentChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reenrichment",
+		Name:      "changes_total",
+		Help:      "Number of attribute values changed by re-enrichment.",
+	}, []string{"field"})
+
+	// Leader 1 while this instance is the leader of the job
+	Leader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "leader",
+		Name:      "is_leader",
+		Help:      "Whether this instance is the leader of the job.",
+	}, []string{"job"})
+
+	// LeaderChanges number of leadership changes by event (acquired, lost, released)
+	LeaderChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "leader",
+		Name:      "changes_total",
+		Help:      "Number of leadership changes by event.",
+	}, []string{"job", "event"})
+)
+
+// Handler serves metrics in Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
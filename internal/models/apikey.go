@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// APIKey stored api key, the key itself is kept only as a hash
+type APIKey struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	Roles     []string   `json:"roles"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
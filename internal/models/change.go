@@ -0,0 +1,8 @@
+package models
+
+// Change of an enriched attribute found by re-enrichment
+type Change struct {
+	Field    string `json:"field"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Contribution answer of one provider used to determine an attribute
+type Contribution struct {
+	Attribute   string   `json:"attribute"`
+	Provider    string   `json:"provider"`
+	Value       string   `json:"value"`
+	Probability *float64 `json:"probability,omitempty"`
+	Weight      float64  `json:"weight"`
+	Count       int      `json:"count"`
+	Country     string   `json:"country,omitempty"`
+}
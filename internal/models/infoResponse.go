@@ -1,14 +1,18 @@
 package models
 
+// AgeResponse agify response, Age is nil when the name is unknown
 type AgeResponse struct {
-	Name string `json:"name"`
-	Age  int    `json:"age"`
+	Name  string `json:"name"`
+	Age   *int   `json:"age"`
+	Count int    `json:"count"`
 }
 
+// GenderResponse genderize response, Gender is nil when the name is unknown
 type GenderResponse struct {
 	Name        string  `json:"name"`
-	Gender      string  `json:"gender"`
+	Gender      *string `json:"gender"`
 	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
 }
 
 type Country struct {
@@ -19,4 +23,5 @@ type Country struct {
 type NationalityResponse struct {
 	Name      string    `json:"name"`
 	Countries []Country `json:"country"`
+	Count     int       `json:"count"`
 }
@@ -1,19 +1,69 @@
 package models
 
+import "time"
+
+const (
+	LocalizationHint        = "hint"
+	LocalizationNationality = "nationality"
+)
+
 // Person personal data
 type Person struct {
 	Name       string `json:"name"`
 	Surname    string `json:"surname"`
 	Patronymic string `json:"patronymic"`
+	// CountryHint ISO 3166-1 alpha-2 country used to localize age and gender on create
+	CountryHint string `json:"country_hint,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+	// FullName is parsed into name, surname and patronymic on create, Locale selects its ordering conventions
+	// and is detected by script when empty
+	FullName string `json:"full_name,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// ParsedName parts of full name, Order lists them as S surname, N name and P patronymic
+type ParsedName struct {
+	Name       string  `json:"name"`
+	Surname    string  `json:"surname"`
+	Patronymic string  `json:"patronymic"`
+	Locale     string  `json:"locale"`
+	Order      string  `json:"order"`
+	Confidence float64 `json:"confidence"`
 }
 
-// PersonInfo information about person
+// PersonInfo information about person, unknown age and gender are null
 type PersonInfo struct {
-	Name              string    `json:"name"`
-	Surname           string    `json:"surname"`
-	Patronymic        string    `json:"patronymic"`
-	Age               int       `json:"age"`
-	Gender            string    `json:"gender"`
-	GenderProbability float64   `json:"gender_probability"`
-	Nationality       []Country `json:"nationality"`
+	Name              string     `json:"name"`
+	Surname           string     `json:"surname"`
+	Patronymic        string     `json:"patronymic"`
+	Age               *int       `json:"age"`
+	Gender            *string    `json:"gender"`
+	GenderProbability *float64   `json:"gender_probability"`
+	Nationality       []Country  `json:"nationality"`
+	Provenance        Provenance `json:"provenance"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	// Contributions answers of every provider, filled only when explanation is requested
+	Contributions []Contribution `json:"contributions,omitempty"`
+}
+
+// Source provider which produced attribute value, when and from how many samples
+type Source struct {
+	Provider   string     `json:"provider"`
+	Count      int        `json:"count"`
+	EnrichedAt *time.Time `json:"enriched_at"`
+	// Country the request to provider was localized with
+	Country string `json:"country,omitempty"`
+}
+
+// Provenance sources of enriched attributes
+type Provenance struct {
+	Age         Source `json:"age"`
+	Gender      Source `json:"gender"`
+	Nationality Source `json:"nationality"`
+	// Localization where the country of age and gender requests came from: hint, nationality or empty
+	Localization string `json:"localization,omitempty"`
+	// CountryHint country given by client on create, kept even if localized requests fell back to global ones
+	CountryHint string `json:"country_hint,omitempty"`
+	// GenderConflict genderize and local rules disagreed about gender
+	GenderConflict bool `json:"gender_conflict,omitempty"`
 }
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory limiter for a single instance
+type Memory struct {
+	mu          sync.Mutex
+	windows     map[string]*window
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+type window struct {
+	start time.Time
+	end   time.Time
+	count int
+}
+
+func NewMemory() *Memory {
+	return &Memory{windows: make(map[string]*window), now: time.Now}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastCleanup) > limit.Window {
+		m.cleanup(now)
+	}
+
+	start := now.Truncate(limit.Window)
+	w, ok := m.windows[key]
+	if !ok || !w.start.Equal(start) {
+		w = &window{start: start, end: start.Add(limit.Window)}
+		m.windows[key] = w
+	}
+	w.count++
+	return result(w.count, limit, w.end.Sub(now)), nil
+}
+
+// cleanup removes finished windows
+func (m *Memory) cleanup(now time.Time) {
+	for key, w := range m.windows {
+		if !now.Before(w.end) {
+			delete(m.windows, key)
+		}
+	}
+	m.lastCleanup = now
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	now := time.Date(2025, 5, 16, 12, 0, 10, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Window: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		r, _ := m.Allow(context.Background(), "client", limit)
+		if !r.Allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	r, _ := m.Allow(context.Background(), "client", limit)
+	if r.Allowed || r.Remaining != 0 {
+		t.Errorf("Expected third request to be limited, got %+v", r)
+	}
+	if r.Reset != 50*time.Second {
+		t.Errorf("Expected reset in 50s, got %v", r.Reset)
+	}
+
+	r, _ = m.Allow(context.Background(), "other", limit)
+	if !r.Allowed || r.Remaining != 1 {
+		t.Errorf("Expected other key to have own budget, got %+v", r)
+	}
+
+	now = now.Add(time.Minute)
+	r, _ = m.Allow(context.Background(), "client", limit)
+	if !r.Allowed {
+		t.Errorf("Expected request in the next window to be allowed, got %+v", r)
+	}
+}
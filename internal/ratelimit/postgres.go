@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres limiter shares counters between instances through the rate_limits table
+type Postgres struct {
+	db          *pgxpool.Pool
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewPostgres(db *pgxpool.Pool) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	p.cleanup(ctx, limit.Window)
+
+	// window boundaries are computed by the database so that instances agree on them regardless of their clocks
+	var count int
+	var reset float64
+	err := p.db.QueryRow(ctx, `WITH w AS (
+		SELECT to_timestamp(floor(extract(epoch FROM now())::float8 / $2::float8) * $2::float8) AS start
+	)
+	INSERT INTO rate_limits (key, window_start, count)
+	SELECT $1, w.start, 1 FROM w
+	ON CONFLICT (key, window_start) DO UPDATE SET count = rate_limits.count + 1
+	RETURNING count, extract(epoch FROM window_start + make_interval(secs => $2::float8) - now())::float8`,
+		key, limit.Window.Seconds()).Scan(&count, &reset)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count request: %w", err)
+	}
+	return result(count, limit, time.Duration(reset*float64(time.Second))), nil
+}
+
+// cleanup removes finished windows at most once per window
+func (p *Postgres) cleanup(ctx context.Context, window time.Duration) {
+	p.mu.Lock()
+	if time.Since(p.lastCleanup) < window {
+		p.mu.Unlock()
+		return
+	}
+	p.lastCleanup = time.Now()
+	p.mu.Unlock()
+
+	_, _ = p.db.Exec(ctx, `DELETE FROM rate_limits WHERE window_start < now() - make_interval(secs => $1::float8)`, window.Seconds())
+}
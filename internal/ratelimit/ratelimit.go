@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+type Config struct {
+	Enabled       bool          `yaml:"RATE_LIMIT_ENABLED" env:"RATE_LIMIT_ENABLED" env-default:"false"`
+	Backend       string        `yaml:"RATE_LIMIT_BACKEND" env:"RATE_LIMIT_BACKEND" env-default:"memory"`
+	Window        time.Duration `yaml:"RATE_LIMIT_WINDOW" env:"RATE_LIMIT_WINDOW" env-default:"1m"`
+	ReadRequests  int           `yaml:"RATE_LIMIT_READ_REQUESTS" env:"RATE_LIMIT_READ_REQUESTS" env-default:"300"`
+	WriteRequests int           `yaml:"RATE_LIMIT_WRITE_REQUESTS" env:"RATE_LIMIT_WRITE_REQUESTS" env-default:"30"`
+}
+
+// Limit number of requests allowed per window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result decision of limiter
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset time left until the current window ends
+	Reset time.Duration
+}
+
+// Limiter counts requests by key in fixed windows
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func result(count int, limit Limit, reset time.Duration) Result {
+	remaining := limit.Requests - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Allowed:   count <= limit.Requests,
+		Limit:     limit.Requests,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
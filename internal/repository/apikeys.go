@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+func (r *repo) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "repository.CreateAPIKey")
+	defer span.End()
+
+	created := *key
+	err := r.db.QueryRow(ctx, `INSERT INTO api_keys (name, key_hash, roles, scopes) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		key.Name, key.Hash, key.Roles, key.Scopes).Scan(&created.Id, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert into api_keys table: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *repo) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "repository.GetAPIKeyByHash")
+	defer span.End()
+
+	var key models.APIKey
+	err := r.db.QueryRow(ctx, `SELECT id, name, key_hash, roles, scopes, created_at, revoked_at FROM api_keys WHERE key_hash = $1`, hash).
+		Scan(&key.Id, &key.Name, &key.Hash, &key.Roles, &key.Scopes, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotExist
+		}
+		return nil, ErrDatabase(err)
+	}
+	return &key, nil
+}
+
+func (r *repo) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "repository.RevokeAPIKey")
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return ErrDatabase(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+// GetContributions returns answers of providers stored with the last enrichment of a person
+func (r *repo) GetContributions(ctx context.Context, id int) ([]models.Contribution, error) {
+	ctx, span := tracer.Start(ctx, "repository.GetContributions")
+	defer span.End()
+
+	exist, err := checkExistence(ctx, r, id)
+	if err != nil {
+		return nil, ErrCheckExistence(err)
+	}
+	if !exist {
+		return nil, ErrNotExist
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT attribute, provider, value, probability, weight, count, country
+		FROM enrichment_contributions WHERE person_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+	defer rows.Close()
+
+	contributions := []models.Contribution{}
+	for rows.Next() {
+		var c models.Contribution
+		if err = rows.Scan(&c.Attribute, &c.Provider, &c.Value, &c.Probability, &c.Weight, &c.Count, &c.Country); err != nil {
+			return nil, ErrDatabase(err)
+		}
+		contributions = append(contributions, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, ErrDatabase(err)
+	}
+	return contributions, nil
+}
+
+// replaceContributions stores contributions of a new enrichment, nationality ones are kept unless keepNationality is false
+func replaceContributions(ctx context.Context, tx pgx.Tx, id int, contributions []models.Contribution, keepNationality bool) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM enrichment_contributions WHERE person_id = $1 AND (attribute <> 'nationality' OR NOT $2)`, id, keepNationality)
+	for _, c := range contributions {
+		batch.Queue(`INSERT INTO enrichment_contributions (person_id, attribute, provider, value, probability, weight, count, country)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, id, c.Attribute, c.Provider, c.Value, c.Probability, c.Weight, c.Count, c.Country)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to replace enrichment contributions: %w", err)
+	}
+	return nil
+}
@@ -5,7 +5,9 @@
 package repository
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	dto "github.com/nutochk/ef-test/internal/dto"
@@ -35,55 +37,85 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClaimStale mocks base method.
+func (m *MockRepository) ClaimStale(ctx context.Context, before time.Time, minProbability float64, recheckBefore time.Time, limit int, lease time.Duration) ([]dto.PersonInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimStale", ctx, before, minProbability, recheckBefore, limit, lease)
+	ret0, _ := ret[0].([]dto.PersonInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimStale indicates an expected call of ClaimStale.
+func (mr *MockRepositoryMockRecorder) ClaimStale(ctx, before, minProbability, recheckBefore, limit, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimStale", reflect.TypeOf((*MockRepository)(nil).ClaimStale), ctx, before, minProbability, recheckBefore, limit, lease)
+}
+
 // Create mocks base method.
-func (m *MockRepository) Create(p *models.PersonInfo) (int, error) {
+func (m *MockRepository) Create(ctx context.Context, p *models.PersonInfo) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", p)
+	ret := m.ctrl.Call(m, "Create", ctx, p)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockRepositoryMockRecorder) Create(p interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Create(ctx, p interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), p)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, p)
 }
 
 // Delete mocks base method.
-func (m *MockRepository) Delete(id int) (bool, error) {
+func (m *MockRepository) Delete(ctx context.Context, id int) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockRepositoryMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, id)
 }
 
 // GetById mocks base method.
-func (m *MockRepository) GetById(id int) (*models.PersonInfo, error) {
+func (m *MockRepository) GetById(ctx context.Context, id int) (*models.PersonInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetById", id)
+	ret := m.ctrl.Call(m, "GetById", ctx, id)
 	ret0, _ := ret[0].(*models.PersonInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetById indicates an expected call of GetById.
-func (mr *MockRepositoryMockRecorder) GetById(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockRepository)(nil).GetById), ctx, id)
+}
+
+// GetContributions mocks base method.
+func (m *MockRepository) GetContributions(ctx context.Context, id int) ([]models.Contribution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContributions", ctx, id)
+	ret0, _ := ret[0].([]models.Contribution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContributions indicates an expected call of GetContributions.
+func (mr *MockRepositoryMockRecorder) GetContributions(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockRepository)(nil).GetById), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContributions", reflect.TypeOf((*MockRepository)(nil).GetContributions), ctx, id)
 }
 
 // GetPeople mocks base method.
-func (m *MockRepository) GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination) (*[]dto.PersonInfo, int, error) {
+func (m *MockRepository) GetPeople(ctx context.Context, filters *dto.PersonFilter, pagination *dto.Pagination) (*[]dto.PersonInfo, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPeople", filters, pagination)
+	ret := m.ctrl.Call(m, "GetPeople", ctx, filters, pagination)
 	ret0, _ := ret[0].(*[]dto.PersonInfo)
 	ret1, _ := ret[1].(int)
 	ret2, _ := ret[2].(error)
@@ -91,22 +123,51 @@ func (m *MockRepository) GetPeople(filters *dto.PersonFilter, pagination *dto.Pa
 }
 
 // GetPeople indicates an expected call of GetPeople.
-func (mr *MockRepositoryMockRecorder) GetPeople(filters, pagination interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetPeople(ctx, filters, pagination interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPeople", reflect.TypeOf((*MockRepository)(nil).GetPeople), ctx, filters, pagination)
+}
+
+// GetStats mocks base method.
+func (m *MockRepository) GetStats(ctx context.Context, filters *dto.PersonFilter, query *dto.StatsQuery) (*dto.PeopleStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx, filters, query)
+	ret0, _ := ret[0].(*dto.PeopleStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockRepositoryMockRecorder) GetStats(ctx, filters, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockRepository)(nil).GetStats), ctx, filters, query)
+}
+
+// SaveEnrichment mocks base method.
+func (m *MockRepository) SaveEnrichment(ctx context.Context, id int, p *models.PersonInfo, changes []models.Change) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveEnrichment", ctx, id, p, changes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveEnrichment indicates an expected call of SaveEnrichment.
+func (mr *MockRepositoryMockRecorder) SaveEnrichment(ctx, id, p, changes interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPeople", reflect.TypeOf((*MockRepository)(nil).GetPeople), filters, pagination)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEnrichment", reflect.TypeOf((*MockRepository)(nil).SaveEnrichment), ctx, id, p, changes)
 }
 
 // Update mocks base method.
-func (m *MockRepository) Update(id int, i *models.Person) (*models.PersonInfo, error) {
+func (m *MockRepository) Update(ctx context.Context, id int, i *models.Person) (*models.PersonInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", id, i)
+	ret := m.ctrl.Call(m, "Update", ctx, id, i)
 	ret0, _ := ret[0].(*models.PersonInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockRepositoryMockRecorder) Update(id, i interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Update(ctx, id, i interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), id, i)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, id, i)
 }
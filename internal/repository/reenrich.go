@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/models"
+	"go.uber.org/zap"
+)
+
+// ClaimStale leases up to limit people enriched before the given time or with gender probability below minProbability.
+// People re-enriched (or, if never, enriched) after recheckBefore are skipped, so rows which stay stale after
+// re-enrichment, e.g. with low probability or unknown attributes, are not claimed on every run.
+// Leased rows are skipped by other callers until the lease expires, so several replicas can claim concurrently.
+func (r *repo) ClaimStale(ctx context.Context, before time.Time, minProbability float64, recheckBefore time.Time, limit int, lease time.Duration) ([]dto.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "repository.ClaimStale")
+	defer span.End()
+
+	query := `WITH stale AS (
+		SELECT i.person_id FROM info i
+		WHERE (` + enrichedAtExpr + ` < $1 OR COALESCE(i.gender_probability, 0) < $2)
+			AND COALESCE(i.reenriched_at, i.gender_enriched_at, '-infinity') < $5
+			AND (i.reenrich_lease_until IS NULL OR i.reenrich_lease_until < now())
+		ORDER BY ` + enrichedAtExpr + `
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE info i SET reenrich_lease_until = now() + make_interval(secs => $4::float8)
+	FROM stale, people p
+	WHERE i.person_id = stale.person_id AND p.id = i.person_id
+	RETURNING p.id, p.name, p.surname, p.patronymic, p.created_at, p.updated_at, ` + infoColumns
+
+	rows, err := r.db.Query(ctx, query, before, minProbability, limit, lease.Seconds(), recheckBefore)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+	defer rows.Close()
+
+	var persons []dto.PersonInfo
+	for rows.Next() {
+		var p dto.PersonInfo
+		var pi models.PersonInfo
+		err = rows.Scan(append([]any{&p.Id, &p.Name, &p.Surname, &p.Patronymic, &p.CreatedAt, &p.UpdatedAt}, infoFields(&pi)...)...)
+		if err != nil {
+			return nil, ErrDatabase(err)
+		}
+		p.Age, p.Gender, p.GenderProbability, p.Provenance = pi.Age, pi.Gender, pi.GenderProbability, pi.Provenance
+		persons = append(persons, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, ErrDatabase(err)
+	}
+
+	for i := range persons {
+		persons[i].Nationality, err = r.countries(ctx, persons[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.log(ctx).Debug("stale people claimed", zap.Int("count", len(persons)))
+	return persons, nil
+}
+
+// SaveEnrichment stores re-enriched attributes with the list of changes and releases the lease.
+// Nationality is kept when p has no nationality provider, i.e. the provider failed.
+func (r *repo) SaveEnrichment(ctx context.Context, id int, p *models.PersonInfo, changes []models.Change) error {
+	ctx, span := tracer.Start(ctx, "repository.SaveEnrichment")
+	defer span.End()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return ErrBeginTransaction(err)
+	}
+	defer tx.Rollback(ctx)
+
+	src := &p.Provenance
+	tag, err := tx.Exec(ctx, `UPDATE info SET age = $2, gender = $3, gender_probability = $4,
+		age_provider = $5, age_count = $6, age_enriched_at = $7,
+		gender_provider = $8, gender_count = $9, gender_enriched_at = $10,
+		age_country = $11, gender_country = $12, localization = $13, gender_conflict = $14,
+		reenrich_lease_until = NULL, reenriched_at = now()
+		WHERE person_id = $1`,
+		id, p.Age, p.Gender, p.GenderProbability,
+		src.Age.Provider, src.Age.Count, src.Age.EnrichedAt,
+		src.Gender.Provider, src.Gender.Count, src.Gender.EnrichedAt,
+		src.Age.Country, src.Gender.Country, src.Localization, src.GenderConflict)
+	if err != nil {
+		return fmt.Errorf("failed to update info table: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotExist
+	}
+
+	if src.Nationality.Provider != "" {
+		_, err = tx.Exec(ctx, `UPDATE info SET nationality_provider = $2, nationality_count = $3, nationality_enriched_at = $4
+			WHERE person_id = $1`, id, src.Nationality.Provider, src.Nationality.Count, src.Nationality.EnrichedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update info table: %w", err)
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM countries WHERE person_id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete from countries table: %w", err)
+		}
+		for _, n := range p.Nationality {
+			_, err = tx.Exec(ctx, `INSERT INTO countries (person_id, nationality, probability) VALUES ($1, $2, $3)`, id, n.CountryId, n.Probability)
+			if err != nil {
+				return fmt.Errorf("failed to insert into countries table: %w", err)
+			}
+		}
+	}
+
+	if err = replaceContributions(ctx, tx, id, p.Contributions, src.Nationality.Provider == ""); err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		batch := &pgx.Batch{}
+		for _, c := range changes {
+			batch.Queue(`INSERT INTO enrichment_changes (person_id, field, old_value, new_value) VALUES ($1, $2, $3, $4)`,
+				id, c.Field, c.OldValue, c.NewValue)
+		}
+		batch.Queue(`UPDATE people SET updated_at = now() WHERE id = $1`, id)
+		if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("failed to insert into enrichment_changes table: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return ErrCommitTransaction(err)
+	}
+	return nil
+}
+
+func (r *repo) countries(ctx context.Context, id int) ([]models.Country, error) {
+	rows, err := r.db.Query(ctx, `SELECT nationality, probability FROM countries WHERE person_id = $1`, id)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+	defer rows.Close()
+
+	var countries []models.Country
+	for rows.Next() {
+		var c models.Country
+		if err = rows.Scan(&c.CountryId, &c.Probability); err != nil {
+			return nil, ErrDatabase(err)
+		}
+		countries = append(countries, c)
+	}
+	return countries, nil
+}
@@ -3,62 +3,111 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nutochk/ef-test/internal/dto"
 	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/nutochk/ef-test/internal/repository")
+
+const (
+	infoColumns = `i.age, i.gender, i.gender_probability,
+		i.age_provider, i.age_count, i.age_enriched_at,
+		i.gender_provider, i.gender_count, i.gender_enriched_at,
+		i.nationality_provider, i.nationality_count, i.nationality_enriched_at,
+		i.age_country, i.gender_country, i.localization, i.gender_conflict, i.country_hint`
+
+	// enrichedAtExpr time of the oldest enrichment of a person, -infinity if some attribute was never enriched
+	enrichedAtExpr = `LEAST(COALESCE(i.age_enriched_at, '-infinity'), COALESCE(i.gender_enriched_at, '-infinity'), COALESCE(i.nationality_enriched_at, '-infinity'))`
 )
 
 type Repository interface {
-	Create(p *models.PersonInfo) (int, error)
-	Update(id int, i *models.Person) (*models.PersonInfo, error)
-	Delete(id int) (bool, error)
-	GetById(id int) (*models.PersonInfo, error)
-	GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination) (*[]dto.PersonInfo, int, error)
+	Create(ctx context.Context, p *models.PersonInfo) (int, error)
+	Update(ctx context.Context, id int, i *models.Person) (*models.PersonInfo, error)
+	Delete(ctx context.Context, id int) (bool, error)
+	GetById(ctx context.Context, id int) (*models.PersonInfo, error)
+	GetPeople(ctx context.Context, filters *dto.PersonFilter, pagination *dto.Pagination) (*[]dto.PersonInfo, int, error)
+	GetStats(ctx context.Context, filters *dto.PersonFilter, query *dto.StatsQuery) (*dto.PeopleStats, error)
+	ClaimStale(ctx context.Context, before time.Time, minProbability float64, recheckBefore time.Time, limit int, lease time.Duration) ([]dto.PersonInfo, error)
+	SaveEnrichment(ctx context.Context, id int, p *models.PersonInfo, changes []models.Change) error
+	GetContributions(ctx context.Context, id int) ([]models.Contribution, error)
 }
 
 type repo struct {
-	db *pgx.Conn
+	db     *pgxpool.Pool
+	logger logger.Logger
 }
 
-func NewRepo(db *pgx.Conn) *repo {
-	return &repo{db: db}
+func NewRepo(db *pgxpool.Pool, log logger.Logger) *repo {
+	return &repo{db: db, logger: log}
 }
 
-func (r *repo) Create(p *models.PersonInfo) (int, error) {
-	tx, err := r.db.Begin(context.Background())
+// log returns request-scoped logger carried by ctx
+func (r *repo) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, &r.logger)
+}
+
+func (r *repo) Create(ctx context.Context, p *models.PersonInfo) (int, error) {
+	ctx, span := tracer.Start(ctx, "repository.Create")
+	defer span.End()
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return 0, ErrBeginTransaction(err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
 	var id int
-	err = tx.QueryRow(context.Background(), `INSERT INTO people (name,surname, patronymic) VALUES ($1, $2, $3) RETURNING id`, p.Name, p.Surname, p.Patronymic).Scan(&id)
+	err = tx.QueryRow(ctx, `INSERT INTO people (name,surname, patronymic) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`,
+		p.Name, p.Surname, p.Patronymic).Scan(&id, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert into people table: %w", err)
 	}
 
-	_, err = tx.Exec(context.Background(), `INSERT INTO info (person_id, age, gender, gender_probability) VALUES ($1, $2, $3, $4)`, id, p.Age, p.Gender, p.GenderProbability)
+	src := &p.Provenance
+	_, err = tx.Exec(ctx, `INSERT INTO info (person_id, age, gender, gender_probability,
+		age_provider, age_count, age_enriched_at,
+		gender_provider, gender_count, gender_enriched_at,
+		nationality_provider, nationality_count, nationality_enriched_at,
+		age_country, gender_country, localization, gender_conflict, country_hint)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+		id, p.Age, p.Gender, p.GenderProbability,
+		src.Age.Provider, src.Age.Count, src.Age.EnrichedAt,
+		src.Gender.Provider, src.Gender.Count, src.Gender.EnrichedAt,
+		src.Nationality.Provider, src.Nationality.Count, src.Nationality.EnrichedAt,
+		src.Age.Country, src.Gender.Country, src.Localization, src.GenderConflict, src.CountryHint)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert into info table: %w", err)
 	}
 
 	for _, n := range p.Nationality {
-		_, err = tx.Exec(context.Background(), `INSERT INTO countries (person_id, nationality, probability) VALUES ($1, $2, $3)`, id, n.CountryId, n.Probability)
+		_, err = tx.Exec(ctx, `INSERT INTO countries (person_id, nationality, probability) VALUES ($1, $2, $3)`, id, n.CountryId, n.Probability)
 		if err != nil {
 			return 0, fmt.Errorf("failed to insert into info table: %w", err)
 		}
 	}
+	if err = replaceContributions(ctx, tx, id, p.Contributions, false); err != nil {
+		return 0, err
+	}
 
-	err = tx.Commit(context.Background())
+	err = tx.Commit(ctx)
 	if err != nil {
 		return 0, ErrCommitTransaction(err)
 	}
+	r.log(ctx).Debug("person inserted", zap.Int("person_id", id), zap.Int("countries", len(p.Nationality)))
 	return id, nil
 }
 
-func (r *repo) Update(id int, p *models.Person) (*models.PersonInfo, error) {
-	exist, err := checkExistence(r, id)
+func (r *repo) Update(ctx context.Context, id int, p *models.Person) (*models.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "repository.Update")
+	defer span.End()
+
+	exist, err := checkExistence(ctx, r, id)
 	if err != nil {
 		return nil, ErrCheckExistence(err)
 	}
@@ -66,27 +115,28 @@ func (r *repo) Update(id int, p *models.Person) (*models.PersonInfo, error) {
 		return nil, ErrNotExist
 	}
 
-	tx, err := r.db.Begin(context.Background())
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, ErrBeginTransaction(err)
 	}
-	defer tx.Rollback(context.Background())
-
-	_, err = tx.Exec(context.Background(), `UPDATE people SET name =$1, surname = $2, patronymic = $3 WHERE id = $4`, p.Name, p.Surname, p.Patronymic, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update info table: %w", err)
-	}
+	defer tx.Rollback(ctx)
 
 	var pi models.PersonInfo
 	pi.Name = p.Name
 	pi.Surname = p.Surname
 	pi.Patronymic = p.Patronymic
-	err = tx.QueryRow(context.Background(), `SELECT age, gender, gender_probability FROM info WHERE person_id = $1`, id).Scan(&pi.Age, &pi.Gender, &pi.GenderProbability)
+	err = tx.QueryRow(ctx, `UPDATE people SET name =$1, surname = $2, patronymic = $3, updated_at = now() WHERE id = $4
+		RETURNING created_at, updated_at`, p.Name, p.Surname, p.Patronymic, id).Scan(&pi.CreatedAt, &pi.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update info table: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `SELECT `+infoColumns+` FROM info i WHERE i.person_id = $1`, id).Scan(infoFields(&pi)...)
 	if err != nil {
 		return nil, ErrDatabase(err)
 	}
 
-	rows, err := tx.Query(context.Background(), `SELECT nationality, probability FROM countries WHERE person_id = $1`, id)
+	rows, err := tx.Query(ctx, `SELECT nationality, probability FROM countries WHERE person_id = $1`, id)
 	if err != nil {
 		return nil, ErrDatabase(err)
 	}
@@ -101,52 +151,33 @@ func (r *repo) Update(id int, p *models.Person) (*models.PersonInfo, error) {
 		pi.Nationality = append(pi.Nationality, c)
 	}
 
-	err = tx.Commit(context.Background())
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, ErrCommitTransaction(err)
 	}
 	return &pi, nil
 }
 
-func (r *repo) Delete(id int) (bool, error) {
-	exist, err := checkExistence(r, id)
-	if err != nil {
-		return false, ErrCheckExistence(err)
-	}
-	if !exist {
-		return false, ErrNotExist
-	}
-
-	tx, err := r.db.Begin(context.Background())
-	if err != nil {
-		return false, ErrBeginTransaction(err)
-	}
-	defer tx.Rollback(context.Background())
-
-	_, err = tx.Exec(context.Background(), `DELETE FROM countries WHERE person_id = $1`, id)
-	if err != nil {
-		return false, fmt.Errorf("failed to delete from info table: %w", err)
-	}
-
-	_, err = tx.Exec(context.Background(), `DELETE FROM info WHERE person_id = $1`, id)
-	if err != nil {
-		return false, fmt.Errorf("failed to delete from info table: %w", err)
-	}
+func (r *repo) Delete(ctx context.Context, id int) (bool, error) {
+	ctx, span := tracer.Start(ctx, "repository.Delete")
+	defer span.End()
 
-	_, err = tx.Exec(context.Background(), `DELETE FROM people WHERE id = $1`, id)
+	// info and countries rows are removed by ON DELETE CASCADE
+	tag, err := r.db.Exec(ctx, `DELETE FROM people WHERE id = $1`, id)
 	if err != nil {
 		return false, fmt.Errorf("failed to delete from people table: %w", err)
 	}
-
-	err = tx.Commit(context.Background())
-	if err != nil {
-		return false, ErrCommitTransaction(err)
+	if tag.RowsAffected() == 0 {
+		return false, ErrNotExist
 	}
 	return true, nil
 }
 
-func (r *repo) GetById(id int) (*models.PersonInfo, error) {
-	exist, err := checkExistence(r, id)
+func (r *repo) GetById(ctx context.Context, id int) (*models.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "repository.GetById")
+	defer span.End()
+
+	exist, err := checkExistence(ctx, r, id)
 	if err != nil {
 		return nil, ErrCheckExistence(err)
 	}
@@ -155,16 +186,16 @@ func (r *repo) GetById(id int) (*models.PersonInfo, error) {
 	}
 
 	var p models.PersonInfo
-	query := `SELECT p.name, p.surname, p.patronymic, i.age, i.gender, i.gender_probability 
+	query := `SELECT p.name, p.surname, p.patronymic, p.created_at, p.updated_at, ` + infoColumns + `
 		FROM people p 
 		JOIN info i ON p.id = i.person_id
 		WHERE p.id = $1`
-	err = r.db.QueryRow(context.Background(), query, id).Scan(&p.Name, &p.Surname, &p.Patronymic, &p.Age, &p.Gender, &p.GenderProbability)
+	err = r.db.QueryRow(ctx, query, id).Scan(append([]any{&p.Name, &p.Surname, &p.Patronymic, &p.CreatedAt, &p.UpdatedAt}, infoFields(&p)...)...)
 	if err != nil {
 		return nil, ErrDatabase(err)
 	}
 
-	rows, err := r.db.Query(context.Background(), `SELECT nationality, probability FROM countries WHERE person_id = $1`, id)
+	rows, err := r.db.Query(ctx, `SELECT nationality, probability FROM countries WHERE person_id = $1`, id)
 	if err != nil {
 		return nil, ErrDatabase(err)
 	}
@@ -182,8 +213,11 @@ func (r *repo) GetById(id int) (*models.PersonInfo, error) {
 	return &p, nil
 }
 
-func (r *repo) GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination) (*[]dto.PersonInfo, int, error) {
-	selectQuery := `SELECT p.id, p.name, p.surname, p.patronymic, i.age, i.gender, i.gender_probability
+func (r *repo) GetPeople(ctx context.Context, filters *dto.PersonFilter, pagination *dto.Pagination) (*[]dto.PersonInfo, int, error) {
+	ctx, span := tracer.Start(ctx, "repository.GetPeople")
+	defer span.End()
+
+	selectQuery := `SELECT p.id, p.name, p.surname, p.patronymic, p.created_at, p.updated_at, ` + infoColumns + `
 	FROM people p
 	JOIN info i ON p.id = i.person_id
 	WHERE 1 = 1`
@@ -196,16 +230,23 @@ func (r *repo) GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination)
 	WHERE 1 = 1`
 
 	var total int
-	err := r.db.QueryRow(context.Background(), countQuery+filterQuery, *args...).Scan(&total)
+	err := r.db.QueryRow(ctx, countQuery+filterQuery, *args...).Scan(&total)
+	if err != nil {
+		return nil, 0, ErrDatabase(err)
+	}
 
 	pagQuery, limit, offset := addPagination(pagination, len(*args)+1)
 	*args = append(*args, limit, offset)
 
-	fmt.Println(limit, offset)
-	fmt.Println(pagination.PerPage, pagination.Page)
-	fmt.Println(args)
+	log := r.log(ctx)
+	log.Debug("selecting people",
+		zap.Int("page", pagination.Page),
+		zap.Int("per_page", pagination.PerPage),
+		zap.Int("limit", limit),
+		zap.Int("offset", offset),
+	)
 
-	rows, err := r.db.Query(context.Background(), selectQuery+filterQuery+pagQuery, *args...)
+	rows, err := r.db.Query(ctx, selectQuery+filterQuery+pagQuery, *args...)
 	if err != nil {
 		return nil, 0, ErrDatabase(err)
 	}
@@ -214,17 +255,17 @@ func (r *repo) GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination)
 	var persons []dto.PersonInfo
 	for rows.Next() {
 		var p dto.PersonInfo
-		err = rows.Scan(&p.Id, &p.Name, &p.Surname, &p.Patronymic, &p.Age, &p.Gender, &p.GenderProbability)
+		var pi models.PersonInfo
+		err = rows.Scan(append([]any{&p.Id, &p.Name, &p.Surname, &p.Patronymic, &p.CreatedAt, &p.UpdatedAt}, infoFields(&pi)...)...)
 		if err != nil {
 			return nil, 0, ErrDatabase(err)
 		}
+		p.Age, p.Gender, p.GenderProbability, p.Provenance = pi.Age, pi.Gender, pi.GenderProbability, pi.Provenance
 		persons = append(persons, p)
 	}
 
-	fmt.Println(persons)
-
 	for i := 0; i < len(persons); i++ {
-		rows, err = r.db.Query(context.Background(), `SELECT nationality, probability FROM countries WHERE person_id = $1`, persons[i].Id)
+		rows, err = r.db.Query(ctx, `SELECT nationality, probability FROM countries WHERE person_id = $1`, persons[i].Id)
 		if err != nil {
 			return nil, 0, ErrDatabase(err)
 		}
@@ -241,7 +282,7 @@ func (r *repo) GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination)
 		}
 	}
 
-	fmt.Println(persons)
+	log.Debug("people selected", zap.Int("count", len(persons)), zap.Int("total", total))
 
 	return &persons, total, nil
 }
@@ -280,6 +321,34 @@ func addFilters(filters *dto.PersonFilter) (string, *[]interface{}) {
 		args = append(args, filters.Gender)
 		argPos++
 	}
+
+	if filters.AgeKnown != nil {
+		if *filters.AgeKnown {
+			query += " AND i.age IS NOT NULL"
+		} else {
+			query += " AND i.age IS NULL"
+		}
+	}
+
+	if filters.GenderKnown != nil {
+		if *filters.GenderKnown {
+			query += " AND i.gender IS NOT NULL"
+		} else {
+			query += " AND i.gender IS NULL"
+		}
+	}
+
+	if !filters.EnrichedAfter.IsZero() {
+		query += fmt.Sprintf(" AND "+enrichedAtExpr+" >= $%d", argPos)
+		args = append(args, filters.EnrichedAfter)
+		argPos++
+	}
+
+	if !filters.EnrichedBefore.IsZero() {
+		query += fmt.Sprintf(" AND "+enrichedAtExpr+" < $%d", argPos)
+		args = append(args, filters.EnrichedBefore)
+		argPos++
+	}
 	return query, &args
 }
 
@@ -287,8 +356,18 @@ func addPagination(pagination *dto.Pagination, argPos int) (string, int, int) {
 	return fmt.Sprintf(" LIMIT CAST($%d AS INTEGER) OFFSET CAST($%d AS INTEGER)", argPos, argPos+1), pagination.PerPage, (pagination.Page - 1) * pagination.PerPage
 }
 
-func checkExistence(r *repo, id int) (bool, error) {
+func checkExistence(ctx context.Context, r *repo, id int) (bool, error) {
 	var exist bool
-	err := r.db.QueryRow(context.Background(), `SELECT EXISTS(SELECT 1 FROM people WHERE id = $1 )`, id).Scan(&exist)
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM people WHERE id = $1 )`, id).Scan(&exist)
 	return exist, err
 }
+
+// infoFields returns scan destinations matching infoColumns
+func infoFields(p *models.PersonInfo) []any {
+	src := &p.Provenance
+	return []any{&p.Age, &p.Gender, &p.GenderProbability,
+		&src.Age.Provider, &src.Age.Count, &src.Age.EnrichedAt,
+		&src.Gender.Provider, &src.Gender.Count, &src.Gender.EnrichedAt,
+		&src.Nationality.Provider, &src.Nationality.Count, &src.Nationality.EnrichedAt,
+		&src.Age.Country, &src.Gender.Country, &src.Localization, &src.GenderConflict, &src.CountryHint}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nutochk/ef-test/internal/dto"
+)
+
+const filteredPeople = `
+	FROM people p
+	JOIN info i ON p.id = i.person_id
+	WHERE 1 = 1`
+
+func (r *repo) GetStats(ctx context.Context, filters *dto.PersonFilter, query *dto.StatsQuery) (*dto.PeopleStats, error) {
+	ctx, span := tracer.Start(ctx, "repository.GetStats")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, ErrBeginTransaction(err)
+	}
+	defer tx.Rollback(ctx)
+
+	filterQuery, args := addFilters(filters)
+	stats := dto.PeopleStats{
+		AgeHistogram:     []dto.AgeBucket{},
+		Genders:          []dto.GenderStats{},
+		TopNationalities: []dto.NationalityStats{},
+	}
+
+	totalQuery := `SELECT COUNT(*), COALESCE(MIN(i.age), 0), COALESCE(MAX(i.age), 0), COALESCE(AVG(i.age), 0)` + filteredPeople + filterQuery
+	err = tx.QueryRow(ctx, totalQuery, *args...).Scan(&stats.Total, &stats.AgeMin, &stats.AgeMax, &stats.AgeAvg)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+
+	histogramQuery := fmt.Sprintf(`SELECT (i.age / $%d) * $%d AS bucket, COUNT(*)%s%s AND i.age IS NOT NULL
+	GROUP BY bucket
+	ORDER BY bucket`, len(*args)+1, len(*args)+1, filteredPeople, filterQuery)
+	rows, err := tx.Query(ctx, histogramQuery, append(*args, query.BucketSize)...)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+	for rows.Next() {
+		var b dto.AgeBucket
+		if err = rows.Scan(&b.From, &b.Count); err != nil {
+			rows.Close()
+			return nil, ErrDatabase(err)
+		}
+		b.To = b.From + query.BucketSize - 1
+		stats.AgeHistogram = append(stats.AgeHistogram, b)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, ErrDatabase(err)
+	}
+
+	genderQuery := `SELECT COALESCE(i.gender, ''), COUNT(*), COALESCE(AVG(i.gender_probability), 0)` + filteredPeople + filterQuery + `
+	GROUP BY 1
+	ORDER BY 2 DESC, 1`
+	rows, err = tx.Query(ctx, genderQuery, *args...)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+	for rows.Next() {
+		var g dto.GenderStats
+		if err = rows.Scan(&g.Gender, &g.Count, &g.AvgProbability); err != nil {
+			rows.Close()
+			return nil, ErrDatabase(err)
+		}
+		stats.Genders = append(stats.Genders, g)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, ErrDatabase(err)
+	}
+
+	nationalityQuery := fmt.Sprintf(`WITH filtered AS (SELECT p.id%s%s),
+	top AS (
+		SELECT DISTINCT ON (c.person_id) c.person_id, c.nationality, c.probability
+		FROM countries c
+		JOIN filtered f ON f.id = c.person_id
+		ORDER BY c.person_id, c.probability DESC
+	)
+	SELECT nationality, COUNT(*), COALESCE(AVG(probability), 0)
+	FROM top
+	GROUP BY nationality
+	ORDER BY 2 DESC, 1
+	LIMIT $%d`, filteredPeople, filterQuery, len(*args)+1)
+	rows, err = tx.Query(ctx, nationalityQuery, append(*args, query.Top)...)
+	if err != nil {
+		return nil, ErrDatabase(err)
+	}
+	for rows.Next() {
+		var n dto.NationalityStats
+		if err = rows.Scan(&n.CountryId, &n.Count, &n.AvgProbability); err != nil {
+			rows.Close()
+			return nil, ErrDatabase(err)
+		}
+		stats.TopNationalities = append(stats.TopNationalities, n)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, ErrDatabase(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, ErrCommitTransaction(err)
+	}
+	return &stats, nil
+}
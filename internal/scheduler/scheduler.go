@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/metrics"
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/internal/service"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+type Config struct {
+	Enabled        bool          `yaml:"SCHEDULER_ENABLED" env:"SCHEDULER_ENABLED" env-default:"false"`
+	Interval       time.Duration `yaml:"SCHEDULER_INTERVAL" env:"SCHEDULER_INTERVAL" env-default:"1m"`
+	MaxAge         time.Duration `yaml:"SCHEDULER_MAX_AGE" env:"SCHEDULER_MAX_AGE" env-default:"720h"`
+	MinProbability float64       `yaml:"SCHEDULER_MIN_PROBABILITY" env:"SCHEDULER_MIN_PROBABILITY" env-default:"0"`
+	// MinRecheck time after re-enrichment of a person before it can be selected again
+	MinRecheck time.Duration `yaml:"SCHEDULER_MIN_RECHECK" env:"SCHEDULER_MIN_RECHECK" env-default:"24h"`
+	BatchSize  int           `yaml:"SCHEDULER_BATCH_SIZE" env:"SCHEDULER_BATCH_SIZE" env-default:"50"`
+	Lease      time.Duration `yaml:"SCHEDULER_LEASE" env:"SCHEDULER_LEASE" env-default:"5m"`
+	// RatePerMinute number of people re-enriched per minute, every person costs one request to each provider
+	RatePerMinute int `yaml:"SCHEDULER_RATE_PER_MINUTE" env:"SCHEDULER_RATE_PER_MINUTE" env-default:"30"`
+}
+
+// Store claims stale people and saves their new enrichment
+type Store interface {
+	ClaimStale(ctx context.Context, before time.Time, minProbability float64, recheckBefore time.Time, limit int, lease time.Duration) ([]dto.PersonInfo, error)
+	SaveEnrichment(ctx context.Context, id int, p *models.PersonInfo, changes []models.Change) error
+}
+
+// Enricher determines attributes of person by name
+type Enricher interface {
+	Enrich(ctx context.Context, p *models.Person) (*models.PersonInfo, error)
+}
+
+// Scheduler periodically re-enriches people whose enrichment is older than MaxAge
+// or whose gender probability is below MinProbability, at most once per MinRecheck
+type Scheduler struct {
+	cfg      Config
+	store    Store
+	enricher Enricher
+	limiter  *rate.Limiter
+	logger   *logger.Logger
+	now      func() time.Time
+}
+
+func New(cfg Config, store Store, enricher Enricher, log *logger.Logger) *Scheduler {
+	limit := rate.Inf
+	if cfg.RatePerMinute > 0 {
+		limit = rate.Every(time.Minute / time.Duration(cfg.RatePerMinute))
+	}
+	return &Scheduler{
+		cfg:      cfg,
+		store:    store,
+		enricher: enricher,
+		limiter:  rate.NewLimiter(limit, 1),
+		logger:   log.With(zap.String("component", "scheduler")),
+		now:      time.Now,
+	}
+}
+
+// Run processes batches every Interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("re-enrichment failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims one batch of stale people and re-enriches them, returns number of saved people.
+// Processing stops when a provider reports exhausted quota, unprocessed people are retried after the lease expires.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	now := s.now()
+	people, err := s.store.ClaimStale(ctx, now.Add(-s.cfg.MaxAge), s.cfg.MinProbability, now.Add(-s.cfg.MinRecheck), s.cfg.BatchSize, s.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	saved := 0
+	for _, p := range people {
+		if err = s.limiter.Wait(ctx); err != nil {
+			return saved, err
+		}
+		log := s.logger.With(zap.Int("person_id", p.Id))
+		// cached answers may predate the stored ones, so providers are asked again
+		pi, err := s.enricher.Enrich(service.WithoutCache(ctx), &models.Person{Name: p.Name, Surname: p.Surname, Patronymic: p.Patronymic, CountryHint: p.Provenance.CountryHint})
+		if err != nil {
+			metrics.Reenrichments.WithLabelValues("failed").Inc()
+			if errors.Is(err, service.ErrRateLimited) {
+				log.Warn("provider quota exhausted, postponing re-enrichment", zap.Error(err))
+				return saved, nil
+			}
+			log.Error("failed to re-enrich person", zap.Error(err))
+			continue
+		}
+		changes := diff(&p, pi)
+		if err = s.store.SaveEnrichment(ctx, p.Id, pi, changes); err != nil {
+			metrics.Reenrichments.WithLabelValues("failed").Inc()
+			log.Error("failed to save re-enrichment", zap.Error(err))
+			continue
+		}
+		saved++
+		if len(changes) == 0 {
+			metrics.Reenrichments.WithLabelValues("unchanged").Inc()
+			continue
+		}
+		metrics.Reenrichments.WithLabelValues("changed").Inc()
+		for _, c := range changes {
+			metrics.ReenrichmentChanges.WithLabelValues(c.Field).Inc()
+		}
+		log.Info("person re-enriched", zap.Any("changes", changes))
+	}
+	return saved, nil
+}
+
+// diff lists attributes changed between stored and new enrichment,
+// nationality is compared only when the new enrichment has it
+func diff(old *dto.PersonInfo, new *models.PersonInfo) []models.Change {
+	var changes []models.Change
+	add := func(field, o, n string) {
+		if o != n {
+			changes = append(changes, models.Change{Field: field, OldValue: o, NewValue: n})
+		}
+	}
+	add("age", formatAge(old.Age), formatAge(new.Age))
+	add("gender", formatGender(old.Gender), formatGender(new.Gender))
+	add("gender_probability", formatProbability(old.GenderProbability), formatProbability(new.GenderProbability))
+	if new.Provenance.Nationality.Provider != "" {
+		add("nationality", formatCountries(old.Nationality), formatCountries(new.Nationality))
+	}
+	return changes
+}
+
+// unknown values are recorded as empty strings
+func formatAge(age *int) string {
+	if age == nil {
+		return ""
+	}
+	return strconv.Itoa(*age)
+}
+
+func formatGender(gender *string) string {
+	if gender == nil {
+		return ""
+	}
+	return *gender
+}
+
+func formatProbability(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p, 'f', 2, 64)
+}
+
+func formatCountries(countries []models.Country) string {
+	ids := make([]string, len(countries))
+	for i, c := range countries {
+		ids[i] = c.CountryId
+	}
+	return strings.Join(ids, ",")
+}
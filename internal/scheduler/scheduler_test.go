@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/internal/service"
+	"github.com/nutochk/ef-test/pkg/logger"
+)
+
+type fakeStore struct {
+	people []dto.PersonInfo
+	before time.Time
+	saved  map[int][]models.Change
+}
+
+func (f *fakeStore) ClaimStale(_ context.Context, before time.Time, _ float64, _ time.Time, _ int, _ time.Duration) ([]dto.PersonInfo, error) {
+	f.before = before
+	return f.people, nil
+}
+
+func (f *fakeStore) SaveEnrichment(_ context.Context, id int, _ *models.PersonInfo, changes []models.Change) error {
+	f.saved[id] = changes
+	return nil
+}
+
+type fakeEnricher map[string]*models.PersonInfo
+
+func (f fakeEnricher) Enrich(_ context.Context, p *models.Person) (*models.PersonInfo, error) {
+	if pi, ok := f[p.Name]; ok {
+		return pi, nil
+	}
+	return nil, fmt.Errorf("agify: %w", service.ErrRateLimited)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func newTestScheduler(t *testing.T, store Store, enricher Enricher) *Scheduler {
+	log, err := logger.New(logger.Config{Level: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{MaxAge: time.Hour, BatchSize: 10}, store, enricher, log)
+	s.now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestRunOnce(t *testing.T) {
+	store := &fakeStore{
+		people: []dto.PersonInfo{
+			{Id: 1, Name: "Ivan", Age: ptr(40), Gender: ptr("male"), GenderProbability: ptr(0.99), Nationality: []models.Country{{CountryId: "RU"}}},
+			{Id: 2, Name: "Anna", Age: ptr(30), Gender: ptr("female"), GenderProbability: ptr(0.98)},
+		},
+		saved: map[int][]models.Change{},
+	}
+	nationalize := models.Provenance{Nationality: models.Source{Provider: "nationalize"}}
+	enricher := fakeEnricher{
+		"Ivan": {Age: ptr(42), Gender: ptr("male"), GenderProbability: ptr(0.99), Nationality: []models.Country{{CountryId: "UA"}}, Provenance: nationalize},
+		"Anna": {Age: ptr(30), Gender: ptr("female"), GenderProbability: ptr(0.98)},
+	}
+
+	saved, err := newTestScheduler(t, store, enricher).RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 2 {
+		t.Errorf("expected 2 saved, got %d", saved)
+	}
+	if want := time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC); !store.before.Equal(want) {
+		t.Errorf("expected claim before %v, got %v", want, store.before)
+	}
+	changes := store.saved[1]
+	if len(changes) != 2 || changes[0].Field != "age" || changes[0].NewValue != "42" ||
+		changes[1].Field != "nationality" || changes[1].OldValue != "RU" || changes[1].NewValue != "UA" {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+	if changes, ok := store.saved[2]; !ok || len(changes) != 0 {
+		t.Errorf("expected unchanged person to be saved without changes, got %+v", changes)
+	}
+}
+
+func TestDiffUnknown(t *testing.T) {
+	old := &dto.PersonInfo{Age: ptr(30), Gender: ptr("female"), GenderProbability: ptr(0.6)}
+	changes := diff(old, &models.PersonInfo{})
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	for _, c := range changes {
+		if c.NewValue != "" {
+			t.Errorf("unknown %s must be recorded as empty, got %q", c.Field, c.NewValue)
+		}
+	}
+}
+
+func TestRunOnceStopsOnQuota(t *testing.T) {
+	store := &fakeStore{
+		people: []dto.PersonInfo{{Id: 1, Name: "Limited"}, {Id: 2, Name: "Anna"}},
+		saved:  map[int][]models.Change{},
+	}
+	enricher := fakeEnricher{"Anna": {}}
+
+	saved, err := newTestScheduler(t, store, enricher).RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 0 || len(store.saved) != 0 {
+		t.Errorf("expected batch to stop on exhausted quota, saved %d", saved)
+	}
+}
+
+// recheckStore claims people with gender probability below minProbability like repository does,
+// skipping those saved after recheckBefore
+type recheckStore struct {
+	people []dto.PersonInfo
+	now    time.Time
+	saved  map[int]time.Time
+}
+
+func (f *recheckStore) ClaimStale(_ context.Context, _ time.Time, minProbability float64, recheckBefore time.Time, _ int, _ time.Duration) ([]dto.PersonInfo, error) {
+	var claimed []dto.PersonInfo
+	for _, p := range f.people {
+		if savedAt, ok := f.saved[p.Id]; ok && !savedAt.Before(recheckBefore) {
+			continue
+		}
+		if p.GenderProbability == nil || *p.GenderProbability < minProbability {
+			claimed = append(claimed, p)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *recheckStore) SaveEnrichment(_ context.Context, id int, _ *models.PersonInfo, _ []models.Change) error {
+	f.saved[id] = f.now
+	return nil
+}
+
+func TestRunOnceSkipsRecentlyRechecked(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &recheckStore{
+		people: []dto.PersonInfo{{Id: 1, Name: "Sasha", Gender: ptr("male"), GenderProbability: ptr(0.55)}},
+		now:    now,
+		saved:  map[int]time.Time{},
+	}
+	enricher := fakeEnricher{"Sasha": {Gender: ptr("male"), GenderProbability: ptr(0.55)}}
+	s := newTestScheduler(t, store, enricher)
+	s.cfg.MinProbability = 0.9
+	s.cfg.MinRecheck = 24 * time.Hour
+
+	for i, want := range []int{1, 0} {
+		saved, err := s.RunOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved != want {
+			t.Errorf("run %d: expected %d saved, got %d", i+1, want, saved)
+		}
+	}
+
+	later := now.Add(25 * time.Hour)
+	s.now = func() time.Time { return later }
+	store.now = later
+	if saved, err := s.RunOnce(context.Background()); err != nil || saved != 1 {
+		t.Errorf("expected low probability person to be rechecked after SCHEDULER_MIN_RECHECK, saved %d, err %v", saved, err)
+	}
+}
+
+type hintEnricher map[string]string
+
+func (f hintEnricher) Enrich(_ context.Context, p *models.Person) (*models.PersonInfo, error) {
+	f[p.Name] = p.CountryHint
+	return &models.PersonInfo{}, nil
+}
+
+func TestRunOnceKeepsCountryHint(t *testing.T) {
+	store := &fakeStore{
+		people: []dto.PersonInfo{
+			{Id: 1, Name: "Hinted", Provenance: models.Provenance{Localization: models.LocalizationHint, Age: models.Source{Country: "KZ"}, CountryHint: "KZ"}},
+			// localized requests fell back to global ones, so only the stored hint remembers the country
+			{Id: 2, Name: "FellBack", Provenance: models.Provenance{CountryHint: "BY"}},
+			{Id: 3, Name: "Localized", Provenance: models.Provenance{Localization: models.LocalizationNationality, Age: models.Source{Country: "RU"}}},
+		},
+		saved: map[int][]models.Change{},
+	}
+	enricher := hintEnricher{}
+
+	if _, err := newTestScheduler(t, store, enricher).RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Hinted": "KZ", "FellBack": "BY", "Localized": ""}
+	for name, hint := range want {
+		if got, ok := enricher[name]; !ok || got != hint {
+			t.Errorf("%s: expected country hint %q, got %q", name, hint, got)
+		}
+	}
+}
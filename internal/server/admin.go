@@ -0,0 +1,118 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/repository"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// GetLogLevel godoc
+// @Summary get log level
+// @Description Returns current minimal level of log entries
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} dto.LogLevel
+// @Router /admin/log-level [get]
+func (server *Server) getLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.LogLevel{Level: server.logger.Level()})
+}
+
+// SetLogLevel godoc
+// @Summary set log level
+// @Description Changes minimal level of log entries at runtime (debug, info, warn, error)
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param level body dto.LogLevel true "Log level"
+// @Success 200 {object} dto.LogLevel
+// @Failure 400 {object} map[string]string "Incorrect level"
+// @Router /admin/log-level [put]
+func (server *Server) setLogLevel(c *gin.Context) {
+	var level dto.LogLevel
+	if err := c.ShouldBindJSON(&level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := server.logger.SetLevel(level.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	server.logger.Info("log level changed", zap.String("level", server.logger.Level()))
+	c.JSON(http.StatusOK, dto.LogLevel{Level: server.logger.Level()})
+}
+
+// CreateAPIKey godoc
+// @Summary create api key
+// @Description Issues a new api key. The key is returned only in this response, only its hash is stored
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param key body dto.CreateAPIKey true "Key parameters"
+// @Success 201 {object} dto.CreatedAPIKey
+// @Failure 400 {object} map[string]string "Incorrect data format"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /admin/api-keys [post]
+func (server *Server) createAPIKey(c *gin.Context) {
+	var params dto.CreateAPIKey
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, role := range params.Roles {
+		if !server.authorizer.IsRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role: " + role})
+			return
+		}
+	}
+	key, stored, err := server.keys.Create(c.Request.Context(), params.Name, params.Roles, params.Scopes)
+	if err != nil {
+		logger.FromContext(c.Request.Context(), server.logger).Error("failed to create api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		return
+	}
+	logger.FromContext(c.Request.Context(), server.logger).Info("api key created", zap.Int("api_key_id", stored.Id))
+	c.JSON(http.StatusCreated, dto.CreatedAPIKey{
+		Id:        stored.Id,
+		Name:      stored.Name,
+		Key:       key,
+		Roles:     stored.Roles,
+		Scopes:    stored.Scopes,
+		CreatedAt: stored.CreatedAt,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary revoke api key
+// @Description Revokes an api key, requests with it are rejected afterwards
+// @Tags admin
+// @Produce  json
+// @Param        id   path      int  true  "API key ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "Incorrect data format"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /admin/api-keys/{id} [delete]
+func (server *Server) revokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Params.ByName("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err = server.keys.Revoke(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logger.FromContext(c.Request.Context(), server.logger).Error("failed to revoke api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+	logger.FromContext(c.Request.Context(), server.logger).Info("api key revoked", zap.Int("api_key_id", id))
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,27 @@
+package server
+
+import "time"
+
+type Config struct {
+	Port              int           `yaml:"PORT" env:"PORT" env-default:"8084"`
+	UnixSocket        string        `yaml:"SERVER_UNIX_SOCKET" env:"SERVER_UNIX_SOCKET"`
+	ReadHeaderTimeout time.Duration `yaml:"SERVER_READ_HEADER_TIMEOUT" env:"SERVER_READ_HEADER_TIMEOUT" env-default:"5s"`
+	ReadTimeout       time.Duration `yaml:"SERVER_READ_TIMEOUT" env:"SERVER_READ_TIMEOUT" env-default:"30s"`
+	WriteTimeout      time.Duration `yaml:"SERVER_WRITE_TIMEOUT" env:"SERVER_WRITE_TIMEOUT" env-default:"30s"`
+	IdleTimeout       time.Duration `yaml:"SERVER_IDLE_TIMEOUT" env:"SERVER_IDLE_TIMEOUT" env-default:"2m"`
+	// RequestTimeout deadline of api request handling, shorter than WriteTimeout so that slow enrichment
+	// still gets an error response instead of a dropped connection
+	RequestTimeout time.Duration `yaml:"SERVER_REQUEST_TIMEOUT" env:"SERVER_REQUEST_TIMEOUT" env-default:"25s"`
+	MaxHeaderBytes int           `yaml:"SERVER_MAX_HEADER_BYTES" env:"SERVER_MAX_HEADER_BYTES" env-default:"1048576"`
+	MaxBodyBytes   int64         `yaml:"SERVER_MAX_BODY_BYTES" env:"SERVER_MAX_BODY_BYTES" env-default:"1048576"`
+	// TrustedProxies addresses or CIDRs of proxies whose X-Forwarded-For is used as client address, none by default
+	TrustedProxies    []string      `yaml:"SERVER_TRUSTED_PROXIES" env:"SERVER_TRUSTED_PROXIES" env-separator:","`
+	TLSCertFile       string        `yaml:"TLS_CERT_FILE" env:"TLS_CERT_FILE"`
+	TLSKeyFile        string        `yaml:"TLS_KEY_FILE" env:"TLS_KEY_FILE"`
+	TLSReloadInterval time.Duration `yaml:"TLS_RELOAD_INTERVAL" env:"TLS_RELOAD_INTERVAL" env-default:"1m"`
+}
+
+// TLS reports whether the server is configured to serve TLS
+func (c Config) TLS() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
@@ -9,33 +9,97 @@ import (
 	"github.com/nutochk/ef-test/internal/dto"
 	"github.com/nutochk/ef-test/internal/models"
 	"github.com/nutochk/ef-test/internal/repository"
+	"github.com/nutochk/ef-test/internal/service"
 )
 
 // CreatePerson godoc
 // @Summary create new record about person
-// @Description Creates a new record with data enrichment from external APIs
+// @Description Creates a new record with data enrichment from external APIs, full_name is parsed into name, surname and patronymic
 // @Tags people
 // @Accept  json
 // @Produce  json
 // @Param person body models.Person true "Personal data"
 // @Success 200 {object} dto.PersonInfo
-// @Failure 400 {string} string "Incorrect data format"
+// @Failure 400 {string} string "Incorrect data format or ambiguous full_name"
+// @Failure 413 {object} map[string]string "Request body too large"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
 // @Failure 500 {string} string "Server error"
 // @Router /api/people [post]
 func (server *Server) create(c *gin.Context) {
 	var person models.Person
 	if err := c.ShouldBindJSON(&person); err != nil {
-		c.String(http.StatusBadRequest, err.Error())
+		bindError(c, err)
 		return
 	}
-	p, err := server.service.Create(&person)
+	p, err := server.service.Create(c.Request.Context(), &person)
 	if err != nil {
+		if errors.Is(err, service.ErrFullName) {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
 		c.String(http.StatusInternalServerError, "failed to create person")
 		return
 	}
+	server.redactPerson(c, p)
 	c.JSON(http.StatusOK, p)
 }
 
+// PreviewEnrichment godoc
+// @Summary preview enrichment of person
+// @Description Determines age, gender and nationality like create does, without storing the person
+// @Tags people
+// @Produce  json
+// @Param name query string true "Name"
+// @Param surname query string false "Surname"
+// @Param patronymic query string false "Patronymic"
+// @Param country query string false "ISO 3166-1 alpha-2 country to localize age and gender"
+// @Param explain query bool false "Include answers of every enrichment provider"
+// @Success 200 {object} dto.EnrichPreview
+// @Failure 400 {string} string "Incorrect data format"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
+// @Failure 500 {string} string "Server error"
+// @Failure 503 {string} string "Enrichment providers rate limit exceeded"
+// @Router /api/enrich [get]
+func (server *Server) preview(c *gin.Context) {
+	var query dto.EnrichQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	pi, err := server.service.Preview(c.Request.Context(), &models.Person{
+		Name:        query.Name,
+		Surname:     query.Surname,
+		Patronymic:  query.Patronymic,
+		CountryHint: query.Country,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrRateLimited) {
+			c.String(http.StatusServiceUnavailable, "enrichment providers rate limit exceeded")
+			return
+		}
+		c.String(http.StatusInternalServerError, "failed to enrich person")
+		return
+	}
+	preview := dto.EnrichPreview{
+		Name:              pi.Name,
+		Surname:           pi.Surname,
+		Patronymic:        pi.Patronymic,
+		Age:               pi.Age,
+		Gender:            pi.Gender,
+		GenderProbability: pi.GenderProbability,
+		Nationality:       pi.Nationality,
+		Provenance:        pi.Provenance,
+	}
+	if query.Explain {
+		preview.Contributions = pi.Contributions
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
 // UpdatePerson godoc
 // @Summary update record about person
 // @Description Updates the record of an existing person
@@ -47,6 +111,10 @@ func (server *Server) create(c *gin.Context) {
 // @Success 200 {object} dto.PersonInfo
 // @Failure 400 {string} string "Incorrect data format"
 // @Failure 404 {string} string "Not found"
+// @Failure 413 {object} map[string]string "Request body too large"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
 // @Failure 500 {string} string "Server error"
 // @Router /api/people/{id} [put]
 func (server *Server) update(c *gin.Context) {
@@ -57,10 +125,10 @@ func (server *Server) update(c *gin.Context) {
 	}
 	var person models.Person
 	if err := c.ShouldBindJSON(&person); err != nil {
-		c.String(http.StatusBadRequest, err.Error())
+		bindError(c, err)
 		return
 	}
-	pi, err := server.service.Update(id, &person)
+	pi, err := server.service.Update(c.Request.Context(), id, &person)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotExist) {
 			c.String(http.StatusNotFound, err.Error())
@@ -69,6 +137,7 @@ func (server *Server) update(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "failed to update person")
 		return
 	}
+	server.redactPersonInfo(c, pi)
 	c.JSON(http.StatusOK, pi)
 }
 
@@ -82,6 +151,9 @@ func (server *Server) update(c *gin.Context) {
 // @Success 204
 // @Failure 400 {string} string "Incorrect data format"
 // @Failure 404 {string} string "Not found"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
 // @Failure 500 {string} string "Server error"
 // @Router /api/people/{id} [delete]
 func (server *Server) delete(c *gin.Context) {
@@ -90,7 +162,7 @@ func (server *Server) delete(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	err = server.service.Delete(id)
+	err = server.service.Delete(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotExist) {
 			c.String(http.StatusNotFound, err.Error())
@@ -109,9 +181,13 @@ func (server *Server) delete(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param        id   path      int  true  "Person ID"
+// @Param        explain   query      bool  false  "Include answers of every enrichment provider"
 // @Success 200 {object} []models.PersonInfo
 // @Failure 400 {string} string "Incorrect data format"
 // @Failure 404 {string} string "Not found"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
 // @Failure 500 {string} string "Server error"
 // @Router /api/people/{id} [get]
 func (server *Server) getById(c *gin.Context) {
@@ -120,7 +196,17 @@ func (server *Server) getById(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	pi, err := server.service.GetById(id)
+	explain := false
+	if v := c.Query("explain"); v != "" {
+		if explain, err = strconv.ParseBool(v); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	pi, err := server.service.GetById(c.Request.Context(), id)
+	if err == nil && explain {
+		pi.Contributions, err = server.service.Explain(c.Request.Context(), id)
+	}
 	if err != nil {
 		if errors.Is(err, repository.ErrNotExist) {
 			c.String(http.StatusNotFound, err.Error())
@@ -129,6 +215,7 @@ func (server *Server) getById(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "failed to get person")
 		return
 	}
+	server.redactPersonInfo(c, pi)
 	c.JSON(http.StatusOK, pi)
 }
 
@@ -143,10 +230,17 @@ func (server *Server) getById(c *gin.Context) {
 // @Param age_min query int false "Minimum age"
 // @Param age_max query int false "Maximum age"
 // @Param gender query string false "Gender filter (male/female)"
+// @Param age_known query bool false "Only people with known (true) or unknown (false) age"
+// @Param gender_known query bool false "Only people with known (true) or unknown (false) gender"
+// @Param enriched_after query string false "Only people with all attributes enriched at or after the time (RFC 3339)"
+// @Param enriched_before query string false "Only people with some attribute enriched before the time or never (RFC 3339)"
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Number of entries per page" default(10)
 // @Success 200 {object} dto.PaginatedResponse
 // @Failure 400 {object} map[string]string "Incorrect filtering parameters"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied or surname filter without pii scope"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
 // @Failure 500 {string} string "Server error"
 // @Router /api/people [get]
 func (server *Server) getPeople(c *gin.Context) {
@@ -155,6 +249,9 @@ func (server *Server) getPeople(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filters"})
 		return
 	}
+	if !server.allowFilters(c, &filters) {
+		return
+	}
 	var pagination dto.Pagination
 	if err := c.ShouldBindQuery(&pagination); err != nil {
 		pagination = dto.Pagination{Page: 1, PerPage: 10}
@@ -166,10 +263,77 @@ func (server *Server) getPeople(c *gin.Context) {
 		pagination.PerPage = 10
 	}
 
-	response, err := server.service.GetPeople(&filters, &pagination)
+	response, err := server.service.GetPeople(c.Request.Context(), &filters, &pagination)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "failed to find people with filters")
 		return
 	}
+	server.redactPage(c, response)
 	c.JSON(http.StatusOK, response)
 }
+
+// GetStats godoc
+// @Summary Get aggregate statistics about people
+// @Description Returns age histogram, gender distribution and top nationalities of people matching filters
+// @Tags people
+// @Accept  json
+// @Produce  json
+// @Param name query string false "Filter by name"
+// @Param surname query string false "Filter by last name"
+// @Param age_min query int false "Minimum age"
+// @Param age_max query int false "Maximum age"
+// @Param gender query string false "Gender filter (male/female)"
+// @Param age_known query bool false "Only people with known (true) or unknown (false) age"
+// @Param gender_known query bool false "Only people with known (true) or unknown (false) gender"
+// @Param enriched_after query string false "Only people with all attributes enriched at or after the time (RFC 3339)"
+// @Param enriched_before query string false "Only people with some attribute enriched before the time or never (RFC 3339)"
+// @Param bucket_size query int false "Width of age histogram bucket" default(10)
+// @Param top query int false "Number of top nationalities" default(5)
+// @Success 200 {object} dto.PeopleStats
+// @Failure 400 {object} map[string]string "Incorrect filtering parameters"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Permission denied or surname filter without pii scope"
+// @Failure 429 {object} map[string]string "Rate limit exceeded"
+// @Failure 500 {string} string "Server error"
+// @Router /api/people/stats [get]
+func (server *Server) getStats(c *gin.Context) {
+	var filters dto.PersonFilter
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filters"})
+		return
+	}
+	if !server.allowFilters(c, &filters) {
+		return
+	}
+	var query dto.StatsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stats parameters"})
+		return
+	}
+	if query.BucketSize <= 0 {
+		query.BucketSize = 10
+	}
+	if query.Top <= 0 {
+		query.Top = 5
+	}
+	if query.Top > 100 {
+		query.Top = 100
+	}
+
+	stats, err := server.service.GetStats(c.Request.Context(), &filters, &query)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to get stats")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// bindError responds to failed body binding
+func bindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+		return
+	}
+	c.String(http.StatusBadRequest, err.Error())
+}
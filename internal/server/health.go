@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nutochk/ef-test/internal/health"
+)
+
+// Liveness godoc
+// @Summary liveness probe
+// @Description Reports that the process is alive
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func (server *Server) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, server.health.Live())
+}
+
+// Readiness godoc
+// @Summary readiness probe
+// @Description Reports whether the service can receive traffic: database is reachable, migrations are applied and server is not draining
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (server *Server) readyz(c *gin.Context) {
+	report := server.health.Ready(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nutochk/ef-test/internal/auth"
+	"github.com/nutochk/ef-test/internal/metrics"
+	"github.com/nutochk/ef-test/internal/ratelimit"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// metricsMiddleware records count and latency of requests by route template
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// loggerMiddleware assigns or propagates request id, puts request-scoped logger
+// into the request context and writes an access log entry
+func loggerMiddleware(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		fields := []zap.Field{
+			zap.String("request_id", requestID),
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+		requestLog := log.With(fields...)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), requestLog))
+
+		c.Next()
+
+		logger.FromContext(c.Request.Context(), requestLog).Info("request handled",
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("size", c.Writer.Size()),
+		)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// authMiddleware rejects unauthenticated requests and puts principal into the request context
+func authMiddleware(authenticator auth.Authenticator, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		requestLog := logger.FromContext(ctx, log)
+		principal, err := authenticator.Authenticate(ctx, c.Request)
+		if err != nil {
+			if errors.Is(err, auth.ErrNoCredentials) || errors.Is(err, auth.ErrInvalidCredentials) {
+				requestLog.Warn("authentication failed", zap.Error(err))
+				c.Header("WWW-Authenticate", `Bearer, ApiKey`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			requestLog.Error("failed to authenticate", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to authenticate"})
+			return
+		}
+
+		requestLog = requestLog.With(zap.String("principal", principal.Subject), zap.String("auth_method", principal.Method))
+		ctx = auth.WithPrincipal(ctx, principal)
+		c.Request = c.Request.WithContext(logger.WithContext(ctx, requestLog))
+		c.Next()
+	}
+}
+
+// permissionMiddleware rejects requests of principals without permission
+func permissionMiddleware(authorizer *auth.Authorizer, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		if !ok || !authorizer.Allowed(principal, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied: " + permission})
+			return
+		}
+		c.Next()
+	}
+}
+
+// enrichRoute previews enrichment, it calls providers like create and is charged to the write budget
+const enrichRoute = "/enrich"
+
+// rateLimitMiddleware limits requests per caller, read and write requests have separate budgets.
+// Callers are identified by principal or, when unauthenticated, by client address.
+func rateLimitMiddleware(limiter ratelimit.Limiter, read, write ratelimit.Limit, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budget, limit := "write", write
+		if (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) && c.FullPath() != "/api"+enrichRoute {
+			budget, limit = "read", read
+		}
+		key := "ip:" + c.ClientIP()
+		if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok {
+			key = principal.Method + ":" + principal.Subject
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), budget+":"+key, limit)
+		if err != nil {
+			// failing open keeps the api available when the limiter storage is down
+			logger.FromContext(c.Request.Context(), log).Error("failed to check rate limit", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+		c.Header("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(int(limit.Window.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", reset)
+		if !result.Allowed {
+			c.Header("Retry-After", reset)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// timeoutMiddleware sets deadline of request context, enrichment calls and queries fail once it passes
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// bodyLimitMiddleware rejects requests with declared body larger than limit and caps reading of the rest
+func bodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nutochk/ef-test/internal/auth"
+	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+// canSeePII reports whether the caller may see surnames and patronymics
+func (s *Server) canSeePII(c *gin.Context) bool {
+	if s.auth == nil {
+		return true
+	}
+	principal, ok := auth.PrincipalFromContext(c.Request.Context())
+	return ok && principal.HasScope(auth.ScopePII)
+}
+
+// allowFilters rejects filtering by surname without the pii scope, otherwise masked surnames could be
+// guessed one by one from non-empty results or counts
+func (s *Server) allowFilters(c *gin.Context, filters *dto.PersonFilter) bool {
+	if filters.Surname == "" || s.canSeePII(c) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied: surname filter requires " + auth.ScopePII + " scope"})
+	return false
+}
+
+func (s *Server) redactPersonInfo(c *gin.Context, p *models.PersonInfo) {
+	if s.canSeePII(c) {
+		return
+	}
+	p.Surname = mask(p.Surname)
+	p.Patronymic = mask(p.Patronymic)
+}
+
+func (s *Server) redactPerson(c *gin.Context, p *dto.PersonInfo) {
+	if s.canSeePII(c) {
+		return
+	}
+	p.Surname = mask(p.Surname)
+	p.Patronymic = mask(p.Patronymic)
+	if p.ParsedName != nil {
+		parsed := *p.ParsedName
+		parsed.Surname = mask(parsed.Surname)
+		parsed.Patronymic = mask(parsed.Patronymic)
+		p.ParsedName = &parsed
+	}
+}
+
+func (s *Server) redactPage(c *gin.Context, page *dto.PaginatedResponse) {
+	people, ok := page.Data.(*[]dto.PersonInfo)
+	if !ok || people == nil {
+		return
+	}
+	for i := range *people {
+		s.redactPerson(c, &(*people)[i])
+	}
+}
+
+// mask keeps the first letter and replaces the rest with asterisks
+func mask(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return string(r[0]) + strings.Repeat("*", len(r)-1)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nutochk/ef-test/internal/auth"
+	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/internal/service"
+	"github.com/nutochk/ef-test/pkg/logger"
+)
+
+type principalAuth auth.Principal
+
+func (p principalAuth) Authenticate(context.Context, *http.Request) (*auth.Principal, error) {
+	principal := auth.Principal(p)
+	return &principal, nil
+}
+
+// fakeService answers list and stats requests, other methods are not used
+type fakeService struct {
+	service.Service
+}
+
+func (fakeService) GetPeople(context.Context, *dto.PersonFilter, *dto.Pagination) (*dto.PaginatedResponse, error) {
+	return &dto.PaginatedResponse{Data: &[]dto.PersonInfo{}}, nil
+}
+
+func (fakeService) GetStats(context.Context, *dto.PersonFilter, *dto.StatsQuery) (*dto.PeopleStats, error) {
+	return &dto.PeopleStats{}, nil
+}
+
+func newTestServer(t *testing.T, principal auth.Principal) *Server {
+	log, err := logger.New(logger.Config{Level: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(Config{}, fakeService{}, nil, log,
+		WithAuth(principalAuth(principal), auth.NewAuthorizer(auth.DefaultRolePermissions), nil))
+}
+
+func TestSurnameFilterRequiresPII(t *testing.T) {
+	reader := newTestServer(t, auth.Principal{Subject: "reader", Roles: []string{auth.RoleReader}})
+	pii := newTestServer(t, auth.Principal{Subject: "pii", Roles: []string{auth.RoleReader}, Scopes: []string{auth.ScopePII}})
+
+	tests := []struct {
+		server *Server
+		target string
+		status int
+	}{
+		{reader, "/api/people?surname=Ivanov", http.StatusForbidden},
+		{reader, "/api/people/stats?surname=Ivanov", http.StatusForbidden},
+		{reader, "/api/people?name=Ivan", http.StatusOK},
+		{pii, "/api/people?surname=Ivanov", http.StatusOK},
+		{pii, "/api/people/stats?surname=Ivanov", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.server.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d %s", tt.target, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRedactPersonParsedName(t *testing.T) {
+	s := newTestServer(t, auth.Principal{})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/people", nil)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), &auth.Principal{Subject: "editor", Roles: []string{auth.RoleEditor}}))
+
+	p := &dto.PersonInfo{
+		Name:       "Иван",
+		Surname:    "Иванов",
+		Patronymic: "Иванович",
+		ParsedName: &models.ParsedName{Name: "Иван", Surname: "Иванов", Patronymic: "Иванович", Order: "SNP"},
+	}
+	s.redactPerson(c, p)
+	if p.Surname != "И*****" || p.Patronymic != "И*******" {
+		t.Errorf("expected masked surname and patronymic, got %q %q", p.Surname, p.Patronymic)
+	}
+	if p.ParsedName.Name != "Иван" || p.ParsedName.Surname != "И*****" || p.ParsedName.Patronymic != "И*******" {
+		t.Errorf("expected masked parsed name, got %+v", p.ParsedName)
+	}
+}
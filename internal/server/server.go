@@ -2,21 +2,65 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nutochk/ef-test/internal/auth"
+	"github.com/nutochk/ef-test/internal/health"
+	"github.com/nutochk/ef-test/internal/metrics"
+	"github.com/nutochk/ef-test/internal/ratelimit"
 	"github.com/nutochk/ef-test/internal/service"
+	"github.com/nutochk/ef-test/pkg/logger"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.uber.org/zap"
 )
 
+const serviceName = "people-api"
+
 type Server struct {
+	cfg        Config
 	engine     *gin.Engine
 	service    service.Service
+	health     *health.Health
+	logger     *logger.Logger
+	auth       auth.Authenticator
+	authorizer *auth.Authorizer
+	keys       *auth.APIKeys
+	limiter    ratelimit.Limiter
+	readLimit  ratelimit.Limit
+	writeLimit ratelimit.Limit
 	httpServer *http.Server
 }
 
+type Option func(*Server)
+
+// WithAuth requires authentication and permissions for api and admin routes and enables api key management
+func WithAuth(authenticator auth.Authenticator, authorizer *auth.Authorizer, keys *auth.APIKeys) Option {
+	return func(s *Server) {
+		s.auth = authenticator
+		s.authorizer = authorizer
+		s.keys = keys
+	}
+}
+
+// WithRateLimit limits api requests per caller with separate budgets for read and write routes
+func WithRateLimit(limiter ratelimit.Limiter, read, write ratelimit.Limit) Option {
+	return func(s *Server) {
+		s.limiter = limiter
+		s.readLimit = read
+		s.writeLimit = write
+	}
+}
+
 // @title People API
 // @version 1.0
 // @description API for work with information about people
@@ -33,34 +77,131 @@ type Server struct {
 // @BasePath /api
 // @schemes http
 
-func New(service service.Service) *Server {
-	e := gin.Default()
+func New(cfg Config, service service.Service, health *health.Health, log *logger.Logger, opts ...Option) *Server {
+	e := gin.New()
+	// client address is used as rate limit key and logged, so forwarded headers are trusted only from known proxies
+	if err := e.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies, forwarded headers are ignored", zap.Error(err))
+		_ = e.SetTrustedProxies(nil)
+	}
+	e.Use(otelgin.Middleware(serviceName), loggerMiddleware(log), metricsMiddleware(), gin.Recovery())
+	if cfg.MaxBodyBytes > 0 {
+		e.Use(bodyLimitMiddleware(cfg.MaxBodyBytes))
+	}
 	s := &Server{
+		cfg:     cfg,
 		engine:  e,
 		service: service,
+		health:  health,
+		logger:  log,
 		httpServer: &http.Server{
-			Handler: e,
+			Handler:           e,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
 		},
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	s.registerRouters()
 	return s
 }
 
 func (s *Server) registerRouters() {
 	s.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	s.engine.GET("/metrics", gin.WrapH(metrics.Handler()))
+	s.engine.GET("/healthz", s.healthz)
+	s.engine.GET("/readyz", s.readyz)
 	api := s.engine.Group("/api")
+	if s.auth != nil {
+		api.Use(authMiddleware(s.auth, s.logger))
+	}
+	if s.cfg.RequestTimeout > 0 {
+		api.Use(timeoutMiddleware(s.cfg.RequestTimeout))
+	}
+	if s.limiter != nil {
+		api.Use(rateLimitMiddleware(s.limiter, s.readLimit, s.writeLimit, s.logger))
+	}
+	// admin routes change server state, so they are not exposed without authentication
+	if s.auth != nil {
+		admin := s.engine.Group("/admin", authMiddleware(s.auth, s.logger))
+		admin.GET("/log-level", s.require(auth.PermissionAdmin), s.getLogLevel)
+		admin.PUT("/log-level", s.require(auth.PermissionAdmin), s.setLogLevel)
+		if s.keys != nil {
+			admin.POST("/api-keys", s.require(auth.PermissionAdmin), s.createAPIKey)
+			admin.DELETE("/api-keys/:id", s.require(auth.PermissionAdmin), s.revokeAPIKey)
+		}
+	}
 	{
-		api.POST("/people", s.create)
-		api.PUT("/people/:id", s.update)
-		api.DELETE("/people/:id", s.delete)
-		api.GET("people/:id", s.getById)
-		api.GET("/people", s.getPeople)
+		api.POST("/people", s.require(auth.PermissionPeopleWrite), s.create)
+		api.GET(enrichRoute, s.require(auth.PermissionPeopleWrite), s.preview)
+		api.PUT("/people/:id", s.require(auth.PermissionPeopleWrite), s.update)
+		api.DELETE("/people/:id", s.require(auth.PermissionPeopleDelete), s.delete)
+		api.GET("people/:id", s.require(auth.PermissionPeopleRead), s.getById)
+		api.GET("/people", s.require(auth.PermissionPeopleRead), s.getPeople)
+		api.GET("/people/stats", s.require(auth.PermissionPeopleRead), s.getStats)
 	}
 }
 
-func (s *Server) Run(port int) error {
-	s.httpServer.Addr = ":" + strconv.Itoa(port)
-	return s.httpServer.ListenAndServe()
+// require checks permission of the caller, all requests are allowed when authentication is disabled
+func (s *Server) require(permission string) gin.HandlerFunc {
+	if s.authorizer == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return permissionMiddleware(s.authorizer, permission)
+}
+
+// Run listens on unix socket if it is configured or on tcp port otherwise and serves until Shutdown
+func (s *Server) Run() error {
+	if s.cfg.TLS() {
+		reloader, err := newCertReloader(s.cfg.TLSCertFile, s.cfg.TLSKeyFile, s.cfg.TLSReloadInterval, s.logger)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	if s.cfg.TLS() {
+		err = s.httpServer.ServeTLS(ln, "", "")
+	} else {
+		err = s.httpServer.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Addr describes where the server listens
+func (s *Server) Addr() string {
+	scheme := "http"
+	if s.cfg.TLS() {
+		scheme = "https"
+	}
+	if s.cfg.UnixSocket != "" {
+		return scheme + "+unix://" + s.cfg.UnixSocket
+	}
+	return scheme + "://:" + strconv.Itoa(s.cfg.Port)
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	if s.cfg.UnixSocket == "" {
+		return net.Listen("tcp", ":"+strconv.Itoa(s.cfg.Port))
+	}
+	// socket file left by a previous process prevents listening
+	if err := os.Remove(s.cfg.UnixSocket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return net.Listen("unix", s.cfg.UnixSocket)
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// certReloader serves certificate from files and reloads it when files change,
+// files are checked on handshake at most once per interval
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   *logger.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration, log *logger.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval, logger: log}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat tls file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate, the previous certificate is kept if reload fails
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.interval <= 0 || time.Since(r.checkedAt) < r.interval {
+		return r.cert, nil
+	}
+	r.checkedAt = time.Now()
+	modTime, err := r.latestModTime()
+	if err != nil {
+		r.logger.Warn("failed to check tls certificate", zap.Error(err))
+		return r.cert, nil
+	}
+	if !modTime.After(r.modTime) {
+		return r.cert, nil
+	}
+	if err := r.load(); err != nil {
+		r.logger.Error("failed to reload tls certificate", zap.Error(err))
+		return r.cert, nil
+	}
+	r.logger.Info("tls certificate reloaded")
+	return r.cert, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nutochk/ef-test/internal/metrics"
+)
+
+type noCacheKey struct{}
+
+// WithoutCache makes enrichment with the returned context ask providers instead of using cached responses,
+// e.g. for re-enrichment which must not store a cached answer as a new one; fresh responses are still cached
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cache in-memory storage of provider responses with expiration
+type cache[V any] struct {
+	mu       sync.Mutex
+	provider string
+	ttl      time.Duration
+	size     int
+	items    map[string]cacheItem[V]
+}
+
+type cacheItem[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func newCache[V any](provider string, ttl time.Duration, size int) *cache[V] {
+	return &cache[V]{provider: provider, ttl: ttl, size: size, items: make(map[string]cacheItem[V])}
+}
+
+// get returns cached value of key, nothing is returned when ctx bypasses cache
+func (c *cache[V]) get(ctx context.Context, key string) (V, bool) {
+	var zero V
+	if bypass, _ := ctx.Value(noCacheKey{}).(bool); bypass || c.ttl <= 0 {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if ok && time.Now().After(item.expires) {
+		delete(c.items, key)
+		ok = false
+	}
+	if !ok {
+		metrics.CacheRequests.WithLabelValues(c.provider, "miss").Inc()
+		return zero, false
+	}
+	metrics.CacheRequests.WithLabelValues(c.provider, "hit").Inc()
+	return item.value, true
+}
+
+func (c *cache[V]) set(key string, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size > 0 && len(c.items) >= c.size {
+		c.evict()
+	}
+	c.items[key] = cacheItem[V]{value: value, expires: time.Now().Add(c.ttl)}
+}
+
+// evict removes expired items and, if the cache is still full, an arbitrary one
+func (c *cache[V]) evict() {
+	now := time.Now()
+	for k, item := range c.items {
+		if now.After(item.expires) {
+			delete(c.items, k)
+		}
+	}
+	for k := range c.items {
+		if len(c.items) < c.size {
+			return
+		}
+		delete(c.items, k)
+	}
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheWithoutCache(t *testing.T) {
+	c := newCache[int]("test", time.Hour, 10)
+	c.set("Ivan", 42)
+
+	if v, ok := c.get(context.Background(), "Ivan"); !ok || v != 42 {
+		t.Errorf("expected cached 42, got %v, %v", v, ok)
+	}
+	if _, ok := c.get(WithoutCache(context.Background()), "Ivan"); ok {
+		t.Error("expected cache to be bypassed")
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const datasetProvider = "dataset"
+
+// datasetColumns header of CSV dataset, nationality is written as "UA:0.6;RU:0.3"
+var datasetColumns = []string{"name", "country", "count", "age", "male_ratio", "nationality"}
+
+// DatasetRecord statistics of a name, record with empty country is used for all countries
+type DatasetRecord struct {
+	Name        string           `json:"name"`
+	Country     string           `json:"country"`
+	Count       int              `json:"count"`
+	Age         *float64         `json:"age"`
+	MaleRatio   *float64         `json:"male_ratio"`
+	Nationality []models.Country `json:"nationality"`
+}
+
+// Dataset local name statistics loaded from CSV or JSON file, the file is checked on lookup
+// at most once per interval and reloaded when it changes
+type Dataset struct {
+	path     string
+	interval time.Duration
+	logger   *logger.Logger
+
+	mu        sync.Mutex
+	records   map[string]*DatasetRecord
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// LoadDataset reads dataset from path, nil is returned when path is empty
+func LoadDataset(path string, interval time.Duration, log *logger.Logger) (*Dataset, error) {
+	if path == "" {
+		return nil, nil
+	}
+	d := &Dataset{path: path, interval: interval, logger: log}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Len number of records in dataset
+func (d *Dataset) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.records)
+}
+
+func (d *Dataset) load() error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat dataset: %w", err)
+	}
+	f, err := os.Open(d.path)
+	if err != nil {
+		return fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer f.Close()
+
+	var list []DatasetRecord
+	switch strings.ToLower(filepath.Ext(d.path)) {
+	case ".csv":
+		list, err = readDatasetCSV(f)
+	case ".json":
+		err = json.NewDecoder(f).Decode(&list)
+	default:
+		return fmt.Errorf("unsupported dataset format %q, expected .csv or .json", filepath.Ext(d.path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse dataset: %w", err)
+	}
+
+	records := make(map[string]*DatasetRecord, len(list))
+	for i := range list {
+		r := &list[i]
+		if err = r.validate(); err != nil {
+			return fmt.Errorf("invalid dataset record %d: %w", i+1, err)
+		}
+		sort.Slice(r.Nationality, func(a, b int) bool { return r.Nationality[a].Probability > r.Nationality[b].Probability })
+		records[datasetKey(r.Name, r.Country)] = r
+	}
+	d.records = records
+	d.modTime = info.ModTime()
+	d.checkedAt = time.Now()
+	return nil
+}
+
+func (r *DatasetRecord) validate() error {
+	switch {
+	case r.Name == "":
+		return errors.New("name is empty")
+	case r.Country != "" && len(r.Country) != 2:
+		return fmt.Errorf("country %q is not ISO 3166-1 alpha-2", r.Country)
+	case r.Count < 0:
+		return fmt.Errorf("count %d is negative", r.Count)
+	case r.Age != nil && (*r.Age < 0 || *r.Age > 150):
+		return fmt.Errorf("age %v is out of range 0-150", *r.Age)
+	case r.MaleRatio != nil && (*r.MaleRatio < 0 || *r.MaleRatio > 1):
+		return fmt.Errorf("male_ratio %v is out of range 0-1", *r.MaleRatio)
+	}
+	for _, c := range r.Nationality {
+		if len(c.CountryId) != 2 || c.Probability < 0 || c.Probability > 1 {
+			return fmt.Errorf("invalid nationality %s:%v", c.CountryId, c.Probability)
+		}
+	}
+	return nil
+}
+
+func readDatasetCSV(r io.Reader) ([]DatasetRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(datasetColumns)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range datasetColumns {
+		if strings.TrimSpace(strings.ToLower(header[i])) != column {
+			return nil, fmt.Errorf("expected header %s", strings.Join(datasetColumns, ","))
+		}
+	}
+
+	var list []DatasetRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		r := DatasetRecord{Name: strings.TrimSpace(row[0]), Country: strings.TrimSpace(row[1])}
+		if v := strings.TrimSpace(row[2]); v != "" {
+			if r.Count, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("line %d: count: %w", len(list)+2, err)
+			}
+		}
+		if r.Age, err = parseOptionalFloat(row[3]); err != nil {
+			return nil, fmt.Errorf("line %d: age: %w", len(list)+2, err)
+		}
+		if r.MaleRatio, err = parseOptionalFloat(row[4]); err != nil {
+			return nil, fmt.Errorf("line %d: male_ratio: %w", len(list)+2, err)
+		}
+		for _, pair := range strings.Split(row[5], ";") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			id, probability, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			p, err := strconv.ParseFloat(probability, 64)
+			if !ok || err != nil {
+				return nil, fmt.Errorf("line %d: nationality %q must be COUNTRY:probability", len(list)+2, pair)
+			}
+			r.Nationality = append(r.Nationality, models.Country{CountryId: id, Probability: p})
+		}
+		list = append(list, r)
+	}
+}
+
+func parseOptionalFloat(s string) (*float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func datasetKey(name, country string) string {
+	return strings.ToLower(name) + "|" + strings.ToUpper(country)
+}
+
+// lookup finds record of name having the attribute checked by known, the record localized by country
+// is preferred to the record for all countries; the previous data is kept if reload fails
+func (d *Dataset) lookup(name, country string, known func(*DatasetRecord) bool) *DatasetRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.interval > 0 && time.Since(d.checkedAt) >= d.interval {
+		d.reload()
+	}
+	if country != "" {
+		if r, ok := d.records[datasetKey(name, country)]; ok && known(r) {
+			return r
+		}
+	}
+	if r, ok := d.records[datasetKey(name, "")]; ok && known(r) {
+		return r
+	}
+	return nil
+}
+
+func (d *Dataset) reload() {
+	d.checkedAt = time.Now()
+	info, err := os.Stat(d.path)
+	if err != nil {
+		d.logger.Warn("failed to check dataset", zap.Error(err))
+		return
+	}
+	if !info.ModTime().After(d.modTime) {
+		return
+	}
+	if err = d.load(); err != nil {
+		d.logger.Error("failed to reload dataset", zap.Error(err))
+		return
+	}
+	d.logger.Info("dataset reloaded", zap.Int("records", len(d.records)))
+}
+
+func datasetSource(r *DatasetRecord) models.Source {
+	source := newSource(datasetProvider, r.Count)
+	source.Country = r.Country
+	return source
+}
+
+func (d *Dataset) age(_ context.Context, p *models.Person, country string) (*ageEstimate, error) {
+	r := d.lookup(p.Name, country, func(r *DatasetRecord) bool { return r.Age != nil })
+	if r == nil {
+		return nil, nil
+	}
+	return &ageEstimate{age: int(math.Round(*r.Age)), source: datasetSource(r)}, nil
+}
+
+func (d *Dataset) gender(_ context.Context, p *models.Person, country string) (*genderEstimate, error) {
+	r := d.lookup(p.Name, country, func(r *DatasetRecord) bool { return r.MaleRatio != nil })
+	if r == nil {
+		return nil, nil
+	}
+	opinion := genderOpinion{gender: male, probability: *r.MaleRatio}
+	if *r.MaleRatio < 0.5 {
+		opinion = genderOpinion{gender: female, probability: 1 - *r.MaleRatio}
+	}
+	return &genderEstimate{opinion: opinion, source: datasetSource(r)}, nil
+}
+
+func (d *Dataset) nationality(_ context.Context, p *models.Person) (*nationalityEstimate, error) {
+	r := d.lookup(p.Name, "", func(r *DatasetRecord) bool { return len(r.Nationality) > 0 })
+	if r == nil {
+		return nil, nil
+	}
+	return &nationalityEstimate{countries: r.Nationality, source: datasetSource(r)}, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nutochk/ef-test/internal/models"
+	logger2 "github.com/nutochk/ef-test/pkg/logger"
+)
+
+func TestDatasetCSV(t *testing.T) {
+	log, _ := logger2.New(logger2.Config{Level: "debug"})
+	path := filepath.Join(t.TempDir(), "names.csv")
+	data := "name,country,count,age,male_ratio,nationality\n" +
+		"Olena,,120,34.6,0.02,RU:0.3;UA:0.6\n" +
+		"Olena,UA,80,31,,\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := LoadDataset(path, time.Minute, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	person := &models.Person{Name: "olena"}
+
+	age, _ := dataset.age(ctx, person, "UA")
+	if age == nil || age.age != 31 || age.source.Country != "UA" || age.source.Count != 80 {
+		t.Errorf("expected localized age 31, got %+v", age)
+	}
+	age, _ = dataset.age(ctx, person, "PL")
+	if age == nil || age.age != 35 || age.source.Country != "" {
+		t.Errorf("expected global age 35, got %+v", age)
+	}
+	gender, _ := dataset.gender(ctx, person, "UA")
+	if gender == nil || gender.opinion.gender != female || gender.opinion.probability != 0.98 {
+		t.Errorf("expected global female 0.98, got %+v", gender)
+	}
+	nationality, _ := dataset.nationality(ctx, person)
+	if nationality == nil || nationality.countries[0].CountryId != "UA" {
+		t.Errorf("expected nationality sorted by probability, got %+v", nationality)
+	}
+	if age, _ = dataset.age(ctx, &models.Person{Name: "John"}, ""); age != nil {
+		t.Errorf("expected unknown name, got %+v", age)
+	}
+}
+
+func TestDatasetReload(t *testing.T) {
+	log, _ := logger2.New(logger2.Config{Level: "debug"})
+	path := filepath.Join(t.TempDir(), "names.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "Ivan", "age": 40}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := LoadDataset(path, time.Nanosecond, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(path, []byte(`[{"name": "Ivan", "age": 45}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	age, _ := dataset.age(context.Background(), &models.Person{Name: "Ivan"}, "")
+	if age == nil || age.age != 45 {
+		t.Errorf("expected reloaded age 45, got %+v", age)
+	}
+
+	if err = os.WriteFile(path, []byte(`[{"name": ""}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future = future.Add(time.Hour)
+	if err = os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	age, _ = dataset.age(context.Background(), &models.Person{Name: "Ivan"}, "")
+	if age == nil || age.age != 45 {
+		t.Errorf("invalid file must keep previous data, got %+v", age)
+	}
+}
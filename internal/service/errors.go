@@ -1,6 +1,13 @@
 package service
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrRateLimited provider rejected request because its quota is exhausted
+var ErrRateLimited = errors.New("provider rate limit exceeded")
 
 func ErrRequest(e error) error {
 	return fmt.Errorf("request error: %w", e)
@@ -13,3 +20,10 @@ func ErrResponse(e error) error {
 func ErrParsing(e error) error {
 	return fmt.Errorf("failed to parse json: %w", e)
 }
+
+func ErrStatus(code int) error {
+	if code == http.StatusTooManyRequests {
+		return fmt.Errorf("unexpected response status: %d: %w", code, ErrRateLimited)
+	}
+	return fmt.Errorf("unexpected response status: %d", code)
+}
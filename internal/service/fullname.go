@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+// ErrFullName full name can not be parsed into name, surname and patronymic
+var ErrFullName = errors.New("invalid full_name")
+
+// DefaultNameOrders orders of full name parts by locale: S is surname, N name and P patronymic,
+// the first order of a locale is the preferred one
+var DefaultNameOrders = map[string]string{
+	"ru": "SNP|NPS|SN|NS",
+	"uk": "SNP|NPS|SN|NS",
+	"be": "SNP|NPS|SN|NS",
+	"en": "NS|NPS",
+}
+
+const (
+	// preferredOrderScore bonus of the first order of locale, small enough to lose to any suffix evidence
+	preferredOrderScore = 0.3
+	patronymicScore     = 2.0
+	misplacedScore      = -3.0
+	surnameScore        = 1.0
+)
+
+var nameParts = map[rune]string{'S': PartSurname, 'N': "name", 'P': PartPatronymic}
+
+// ParseNameOrders parses orders like "SNP|NPS" by locale
+func ParseNameOrders(orders map[string]string) (map[string][]string, error) {
+	parsed := make(map[string][]string, len(orders))
+	var errs []error
+	for locale, list := range orders {
+		for _, order := range strings.Split(list, "|") {
+			order = strings.ToUpper(strings.TrimSpace(order))
+			if !validNameOrder(order) {
+				errs = append(errs, fmt.Errorf("locale %s: order %q must contain N and S and optionally P once", locale, order))
+				continue
+			}
+			parsed[locale] = append(parsed[locale], order)
+		}
+	}
+	return parsed, errors.Join(errs...)
+}
+
+func validNameOrder(order string) bool {
+	seen := map[rune]bool{}
+	for _, r := range order {
+		if _, ok := nameParts[r]; !ok || seen[r] {
+			return false
+		}
+		seen[r] = true
+	}
+	return seen['N'] && seen['S']
+}
+
+// nameLocale detects locale of full name by its script
+func nameLocale(fullName string) string {
+	lower := strings.ToLower(fullName)
+	switch {
+	case strings.ContainsRune(lower, 'ў'):
+		return "be"
+	case strings.ContainsAny(lower, "іїєґ"):
+		return "uk"
+	case strings.IndexFunc(lower, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) >= 0:
+		return "ru"
+	}
+	return "en"
+}
+
+// parseFullName splits full name of person into parts by orders of its locale. Every order with the same
+// number of parts is scored by patronymic and surname suffixes of gender rules, the best one is used if
+// its confidence is at least FullNameMinConfidence. Confidence only compares orders, so the best order is
+// also required to have a non-negative score and a patronymic suffix at patronymic position
+func (s *service) parseFullName(p *models.Person) (*models.ParsedName, error) {
+	if p.Name != "" || p.Surname != "" || p.Patronymic != "" {
+		return nil, fmt.Errorf("%w: full_name can not be combined with name, surname or patronymic", ErrFullName)
+	}
+	var tokens []string
+	surname, rest, comma := strings.Cut(p.FullName, ",")
+	if comma {
+		// "Surname, Name Patronymic"
+		tokens = append(strings.Fields(surname), strings.Fields(rest)...)
+		if len(strings.Fields(surname)) != 1 {
+			return nil, fmt.Errorf("%w: expected a single surname before comma", ErrFullName)
+		}
+	} else {
+		tokens = strings.Fields(p.FullName)
+	}
+	if len(tokens) < 2 || len(tokens) > 3 {
+		return nil, fmt.Errorf("%w: expected 2 or 3 parts, got %d", ErrFullName, len(tokens))
+	}
+
+	locale := p.Locale
+	if locale == "" {
+		locale = nameLocale(p.FullName)
+	}
+	orders, ok := s.nameOrders[locale]
+	if !ok {
+		return nil, fmt.Errorf("%w: no name orders for locale %q", ErrFullName, locale)
+	}
+
+	type candidate struct {
+		order string
+		score float64
+	}
+	var candidates []candidate
+	seen := map[string]bool{}
+	for i, order := range orders {
+		if len(order) != len(tokens) {
+			continue
+		}
+		if comma {
+			// surname is moved before comma, other parts keep their order
+			order = "S" + strings.ReplaceAll(order, "S", "")
+		}
+		if seen[order] {
+			continue
+		}
+		seen[order] = true
+		c := candidate{order: order}
+		if i == 0 {
+			c.score = preferredOrderScore
+		}
+		for j, part := range order {
+			c.score += s.partScore(nameParts[part], tokens[j])
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: no %s name order with %d parts", ErrFullName, locale, len(tokens))
+	}
+
+	// softmax of scores gives confidence of every order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if best := candidates[0]; best.score < 0 || !s.hasPatronymic(best.order, tokens) {
+		return nil, fmt.Errorf("%w: %q does not look like %s; pass name, surname and patronymic separately",
+			ErrFullName, p.FullName, describeOrder(best.order, tokens))
+	}
+	var sum float64
+	for _, c := range candidates {
+		sum += math.Exp(c.score - candidates[0].score)
+	}
+	confidence := math.Round(1/sum*100) / 100
+	if confidence < s.cfg.FullNameMinConfidence {
+		interpretations := make([]string, len(candidates))
+		for i, c := range candidates {
+			interpretations[i] = fmt.Sprintf("%s (%.2f)", describeOrder(c.order, tokens), math.Exp(c.score-candidates[0].score)/sum)
+		}
+		return nil, fmt.Errorf("%w: ambiguous %q, possible readings: %s; pass name, surname and patronymic separately",
+			ErrFullName, p.FullName, strings.Join(interpretations, ", "))
+	}
+
+	parsed := models.ParsedName{Locale: locale, Order: candidates[0].order, Confidence: confidence}
+	for j, part := range candidates[0].order {
+		switch part {
+		case 'S':
+			parsed.Surname = tokens[j]
+		case 'N':
+			parsed.Name = tokens[j]
+		case 'P':
+			parsed.Patronymic = tokens[j]
+		}
+	}
+	return &parsed, nil
+}
+
+// hasPatronymic reports whether the token at patronymic position of order, if any, has a patronymic suffix
+func (s *service) hasPatronymic(order string, tokens []string) bool {
+	i := strings.IndexRune(order, 'P')
+	return i < 0 || s.matchesSuffix(PartPatronymic, tokens[i])
+}
+
+// partScore evidence that token is the given part: patronymic suffixes are required at patronymic position
+// and are out of place elsewhere, surname suffixes favour surname position
+func (s *service) partScore(part, token string) float64 {
+	patronymic, surname := s.matchesSuffix(PartPatronymic, token), s.matchesSuffix(PartSurname, token)
+	switch {
+	case part == PartPatronymic && patronymic:
+		return patronymicScore
+	case part == PartPatronymic || patronymic:
+		return misplacedScore
+	case part == PartSurname && surname:
+		return surnameScore
+	case surname:
+		return -surnameScore
+	}
+	return 0
+}
+
+func (s *service) matchesSuffix(part, token string) bool {
+	token = strings.ToLower(token)
+	for _, list := range s.rules {
+		for _, r := range list {
+			if r.Part == part && len(token) > len(r.Suffix) && strings.HasSuffix(token, r.Suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describeOrder(order string, tokens []string) string {
+	parts := make([]string, len(tokens))
+	for j, part := range order {
+		parts[j] = nameParts[part] + "=" + tokens[j]
+	}
+	return strings.Join(parts, " ")
+}
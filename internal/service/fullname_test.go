@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+func TestParseFullName(t *testing.T) {
+	orders, err := ParseNameOrders(DefaultNameOrders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &service{cfg: Config{FullNameMinConfidence: 0.7}, rules: DefaultGenderRules, nameOrders: orders}
+
+	tests := []struct {
+		person                    models.Person
+		name, surname, patronymic string
+		locale                    string
+	}{
+		{models.Person{FullName: "Иванов Иван Иванович"}, "Иван", "Иванов", "Иванович", "ru"},
+		{models.Person{FullName: "Иван Иванович Иванов"}, "Иван", "Иванов", "Иванович", "ru"},
+		{models.Person{FullName: "Петров Иван"}, "Иван", "Петров", "", "ru"},
+		{models.Person{FullName: "Иван Петров"}, "Иван", "Петров", "", "ru"},
+		{models.Person{FullName: "Шевченко Тарас Григорович"}, "Тарас", "Шевченко", "Григорович", "ru"},
+		{models.Person{FullName: "Ковальська Олена Іванівна"}, "Олена", "Ковальська", "Іванівна", "uk"},
+		{models.Person{FullName: "John Smith"}, "John", "Smith", "", "en"},
+		{models.Person{FullName: "Smith, John"}, "John", "Smith", "", "en"},
+		{models.Person{FullName: "Ivanov Ivan Ivanovich", Locale: "ru"}, "Ivan", "Ivanov", "Ivanovich", "ru"},
+	}
+	for _, tt := range tests {
+		parsed, err := svc.parseFullName(&tt.person)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.person.FullName, err)
+			continue
+		}
+		if parsed.Name != tt.name || parsed.Surname != tt.surname || parsed.Patronymic != tt.patronymic || parsed.Locale != tt.locale {
+			t.Errorf("%q: expected %s/%s/%s (%s), got %+v", tt.person.FullName, tt.name, tt.surname, tt.patronymic, tt.locale, parsed)
+		}
+		if parsed.Confidence < 0.7 || parsed.Confidence > 1 {
+			t.Errorf("%q: unexpected confidence %v", tt.person.FullName, parsed.Confidence)
+		}
+	}
+
+	for _, p := range []models.Person{
+		{FullName: "Ким Ли"},
+		{FullName: "Иван"},
+		{FullName: "Иванов Иван Иванович Младший"},
+		{FullName: "Иванов Иван", Name: "Иван"},
+		{FullName: "Jean Dupont", Locale: "fr"},
+		// the only three part en order has no patronymic evidence, so its confidence of 1 is not enough
+		{FullName: "John Paul Smith"},
+		{FullName: "Smith, John Paul"},
+	} {
+		if _, err := svc.parseFullName(&p); !errors.Is(err, ErrFullName) {
+			t.Errorf("%+v: expected ErrFullName, got %v", p, err)
+		}
+	}
+}
+
+func TestParseNameOrders(t *testing.T) {
+	if _, err := ParseNameOrders(map[string]string{"ru": "SNP|NN"}); err == nil {
+		t.Error("expected error for order with repeated part")
+	}
+	if _, err := ParseNameOrders(map[string]string{"en": "NP"}); err == nil {
+		t.Error("expected error for order without surname")
+	}
+}
@@ -1,64 +1,155 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/nutochk/ef-test/internal/metrics"
 	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func getAge(name string) (int, error) {
-	url := fmt.Sprintf("https://api.agify.io/?name=%s", name)
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, ErrRequest(err)
+const (
+	agify       = "agify"
+	genderize   = "genderize"
+	nationalize = "nationalize"
+
+	LocalizationAuto = "auto"
+	LocalizationHint = "hint"
+	LocalizationOff  = "off"
+)
+
+// enriched provider response with its provenance, kept in cache together
+type enriched[V any] struct {
+	value  V
+	source models.Source
+}
+
+func newSource(provider string, count int) models.Source {
+	now := time.Now()
+	return models.Source{Provider: provider, Count: count, EnrichedAt: &now}
+}
+
+// localization chooses country for age and gender requests: the client hint or the top nationality
+// if it is probable enough, returns empty country when requests are not localized
+func (s *service) localization(hint string, countries []models.Country) (string, string) {
+	switch s.cfg.Localization {
+	case LocalizationOff:
+		return "", ""
+	case LocalizationHint, LocalizationAuto:
+		if hint != "" {
+			return hint, models.LocalizationHint
+		}
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, ErrResponse(err)
+	if s.cfg.Localization != LocalizationAuto || len(countries) == 0 {
+		return "", ""
 	}
-	var result models.AgeResponse
-	if err = json.Unmarshal(body, &result); err != nil {
-		return 0, ErrParsing(err)
+	top := countries[0]
+	for _, c := range countries[1:] {
+		if c.Probability > top.Probability {
+			top = c
+		}
+	}
+	if top.Probability < s.cfg.CountryThreshold {
+		return "", ""
 	}
-	return result.Age, nil
+	return top.CountryId, models.LocalizationNationality
 }
 
-func getGender(name string) (string, float64, error) {
-	url := fmt.Sprintf("https://api.genderize.io/?name=%s", name)
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", 0, ErrRequest(err)
+func providerURL(base, name, country string) string {
+	query := url.Values{"name": {name}}
+	if country != "" {
+		query.Set("country_id", country)
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", 0, ErrResponse(err)
+	return strings.TrimSuffix(base, "/") + "/?" + query.Encode()
+}
+
+func (s *service) getAge(ctx context.Context, name, country string) (models.AgeResponse, models.Source, error) {
+	key := name + "|" + country
+	if result, ok := s.ages.get(ctx, key); ok {
+		return result.value, result.source, nil
+	}
+	var result models.AgeResponse
+	if err := s.fetch(ctx, agify, providerURL(s.cfg.AgifyURL, name, country), &result); err != nil {
+		return result, models.Source{}, err
+	}
+	source := newSource(agify, result.Count)
+	source.Country = country
+	s.ages.set(key, enriched[models.AgeResponse]{value: result, source: source})
+	return result, source, nil
+}
+
+func (s *service) getGender(ctx context.Context, name, country string) (models.GenderResponse, models.Source, error) {
+	key := name + "|" + country
+	if result, ok := s.genders.get(ctx, key); ok {
+		return result.value, result.source, nil
 	}
 	var result models.GenderResponse
-	if err = json.Unmarshal(body, &result); err != nil {
-		return "", 0, ErrParsing(err)
+	if err := s.fetch(ctx, genderize, providerURL(s.cfg.GenderizeURL, name, country), &result); err != nil {
+		return result, models.Source{}, err
+	}
+	source := newSource(genderize, result.Count)
+	source.Country = country
+	s.genders.set(key, enriched[models.GenderResponse]{value: result, source: source})
+	return result, source, nil
+}
+
+func (s *service) getCountries(ctx context.Context, name string) (models.NationalityResponse, models.Source, error) {
+	if result, ok := s.nationalities.get(ctx, name); ok {
+		return result.value, result.source, nil
+	}
+	var result models.NationalityResponse
+	if err := s.fetch(ctx, nationalize, providerURL(s.cfg.NationalizeURL, name, ""), &result); err != nil {
+		return result, models.Source{}, err
 	}
-	return result.Gender, result.Probability, nil
+	source := newSource(nationalize, result.Count)
+	s.nationalities.set(name, enriched[models.NationalityResponse]{value: result, source: source})
+	return result, source, nil
 }
 
-func getCountries(name string) ([]models.Country, error) {
-	url := fmt.Sprintf("https://api.nationalize.io/?name=%s", name)
-	resp, err := http.Get(url)
+// fetch requests provider and decodes its json response into result, recording call metrics
+func (s *service) fetch(ctx context.Context, provider, url string, result interface{}) error {
+	ctx, span := tracer.Start(ctx, "enrichment."+provider, trace.WithAttributes(attribute.String("enrichment.provider", provider)))
+	defer span.End()
+
+	start := time.Now()
+	err := s.request(ctx, url, result)
+	metrics.ProviderDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ProviderErrors.WithLabelValues(provider).Inc()
+		tracing.Error(span, err)
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+	return nil
+}
+
+func (s *service) request(ctx context.Context, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, ErrRequest(err)
+		return ErrRequest(err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ErrRequest(err)
 	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, ErrResponse(err)
+		return ErrResponse(err)
 	}
-	var result models.NationalityResponse
-	if err = json.Unmarshal(body, &result); err != nil {
-		return nil, ErrParsing(err)
+	if resp.StatusCode != http.StatusOK {
+		return ErrStatus(resp.StatusCode)
+	}
+	if err = json.Unmarshal(body, result); err != nil {
+		return ErrParsing(err)
 	}
-	return result.Countries, nil
+	return nil
 }
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nutochk/ef-test/internal/models"
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	attributeAge         = "age"
+	attributeGender      = "gender"
+	attributeNationality = "nationality"
+)
+
+// ProviderNames providers which can be listed in ENRICHMENT_*_PROVIDERS by attribute
+var ProviderNames = map[string][]string{
+	attributeAge:         {agify, datasetProvider},
+	attributeGender:      {genderize, localRules, datasetProvider},
+	attributeNationality: {nationalize, datasetProvider},
+}
+
+// providers of attributes, nil estimate means that the provider does not know the value
+type ageProvider interface {
+	age(ctx context.Context, p *models.Person, country string) (*ageEstimate, error)
+}
+
+type genderProvider interface {
+	gender(ctx context.Context, p *models.Person, country string) (*genderEstimate, error)
+}
+
+type nationalityProvider interface {
+	nationality(ctx context.Context, p *models.Person) (*nationalityEstimate, error)
+}
+
+type ageEstimate struct {
+	age    int
+	source models.Source
+}
+
+type genderEstimate struct {
+	opinion genderOpinion
+	source  models.Source
+}
+
+type nationalityEstimate struct {
+	countries []models.Country
+	source    models.Source
+}
+
+// weighted provider or its estimate with the configured weight
+type weighted[T any] struct {
+	name   string
+	weight float64
+	value  T
+}
+
+// resolved fused value of attribute, known is false if no provider knows it. Source of unknown value
+// names the providers which answered without data, it is empty only if no provider was asked or the providers failed
+type resolved[V any] struct {
+	value         V
+	known         bool
+	source        models.Source
+	conflict      bool
+	contributions []models.Contribution
+}
+
+// agifyProvider, genderizeProvider and nationalizeProvider call remote APIs, localized requests
+// that do not know the name are repeated without country
+type agifyProvider struct{ s *service }
+
+type genderizeProvider struct{ s *service }
+
+type nationalizeProvider struct{ s *service }
+
+func (a agifyProvider) age(ctx context.Context, p *models.Person, country string) (*ageEstimate, error) {
+	resp, source, err := a.s.getAge(ctx, p.Name, country)
+	if err == nil && resp.Age == nil && country != "" {
+		resp, source, err = a.s.getAge(ctx, p.Name, "")
+	}
+	if err != nil || resp.Age == nil {
+		return nil, err
+	}
+	return &ageEstimate{age: *resp.Age, source: source}, nil
+}
+
+func (g genderizeProvider) gender(ctx context.Context, p *models.Person, country string) (*genderEstimate, error) {
+	resp, source, err := g.s.getGender(ctx, p.Name, country)
+	if err == nil && resp.Gender == nil && country != "" {
+		resp, source, err = g.s.getGender(ctx, p.Name, "")
+	}
+	if err != nil || resp.Gender == nil {
+		return nil, err
+	}
+	return &genderEstimate{opinion: genderOpinion{gender: *resp.Gender, probability: resp.Probability}, source: source}, nil
+}
+
+func (n nationalizeProvider) nationality(ctx context.Context, p *models.Person) (*nationalityEstimate, error) {
+	resp, source, err := n.s.getCountries(ctx, p.Name)
+	if err != nil || len(resp.Countries) == 0 {
+		return nil, err
+	}
+	return &nationalityEstimate{countries: resp.Countries, source: source}, nil
+}
+
+// selectProviders picks providers of type P by names, providers missing in available or not supporting the attribute are skipped
+func selectProviders[P any](names []string, available map[string]any, weights map[string]float64) []weighted[P] {
+	var selected []weighted[P]
+	for _, name := range names {
+		p, ok := available[name].(P)
+		if !ok {
+			continue
+		}
+		weight, ok := weights[name]
+		if !ok {
+			weight = 1
+		}
+		selected = append(selected, weighted[P]{name: name, weight: weight, value: p})
+	}
+	return selected
+}
+
+// collect asks every provider and returns estimates of those who know the value, error is returned
+// if a provider failed and none of the others knows the value, so that the unknown value is not stored as final
+func collect[P, E any](log *logger.Logger, providers []weighted[P], ask func(P) (*E, error)) ([]weighted[*E], error) {
+	var estimates []weighted[*E]
+	var errs []error
+	for _, p := range providers {
+		e, err := ask(p.value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if e != nil && p.weight > 0 {
+			estimates = append(estimates, weighted[*E]{name: p.name, weight: p.weight, value: e})
+		}
+	}
+	if len(errs) > 0 && len(estimates) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Warn("enrichment provider failed, using the others", zap.Error(err))
+	}
+	return estimates, nil
+}
+
+func (s *service) resolveAge(ctx context.Context, p *models.Person, country string) (resolved[int], error) {
+	var result resolved[int]
+	estimates, err := collect(s.log(ctx), s.ageProviders, func(ap ageProvider) (*ageEstimate, error) {
+		return ap.age(ctx, p, country)
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(estimates) == 0 {
+		result.source = unanswered(s.ageProviders)
+		return result, nil
+	}
+	var sum, weights float64
+	sources := make([]models.Source, len(estimates))
+	for i, e := range estimates {
+		sum += e.weight * float64(e.value.age)
+		weights += e.weight
+		sources[i] = e.value.source
+		result.contributions = append(result.contributions, contribution(attributeAge, e.name, e.weight, e.value.source, strconv.Itoa(e.value.age), nil))
+	}
+	result.value = int(math.Round(sum / weights))
+	result.known = true
+	result.source = mergeSources(sources)
+	return result, nil
+}
+
+func (s *service) resolveGender(ctx context.Context, p *models.Person, country string) (resolved[genderOpinion], error) {
+	var result resolved[genderOpinion]
+	estimates, err := collect(s.log(ctx), s.genderProviders, func(gp genderProvider) (*genderEstimate, error) {
+		return gp.gender(ctx, p, country)
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(estimates) == 0 {
+		result.source = unanswered(s.genderProviders)
+		return result, nil
+	}
+	opinions := make([]genderOpinion, len(estimates))
+	weights := make([]float64, len(estimates))
+	sources := make([]models.Source, len(estimates))
+	for i, e := range estimates {
+		opinions[i], weights[i], sources[i] = e.value.opinion, e.weight, e.value.source
+		probability := e.value.opinion.probability
+		result.contributions = append(result.contributions, contribution(attributeGender, e.name, e.weight, e.value.source, e.value.opinion.gender, &probability))
+	}
+	if len(estimates) == 1 {
+		result.value = opinions[0]
+	} else {
+		result.value, result.conflict = fuseGender(opinions, weights)
+	}
+	if result.conflict {
+		s.log(ctx).Warn("gender providers disagree", zap.Any("contributions", result.contributions), zap.String("chosen", result.value.gender))
+	}
+	result.known = true
+	result.source = mergeSources(sources)
+	return result, nil
+}
+
+func (s *service) resolveNationality(ctx context.Context, p *models.Person) (resolved[[]models.Country], error) {
+	var result resolved[[]models.Country]
+	estimates, err := collect(s.log(ctx), s.nationalityProviders, func(np nationalityProvider) (*nationalityEstimate, error) {
+		return np.nationality(ctx, p)
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(estimates) == 0 {
+		result.source = unanswered(s.nationalityProviders)
+		return result, nil
+	}
+	mixed := map[string]float64{}
+	var weights float64
+	sources := make([]models.Source, len(estimates))
+	for i, e := range estimates {
+		weights += e.weight
+		sources[i] = e.value.source
+		for _, c := range e.value.countries {
+			mixed[c.CountryId] += e.weight * c.Probability
+			probability := c.Probability
+			result.contributions = append(result.contributions, contribution(attributeNationality, e.name, e.weight, e.value.source, c.CountryId, &probability))
+		}
+	}
+	if len(estimates) == 1 {
+		result.value = estimates[0].value.countries
+	} else {
+		for id, p := range mixed {
+			result.value = append(result.value, models.Country{CountryId: id, Probability: math.Round(p/weights*10000) / 10000})
+		}
+		sort.Slice(result.value, func(i, j int) bool {
+			if result.value[i].Probability != result.value[j].Probability {
+				return result.value[i].Probability > result.value[j].Probability
+			}
+			return result.value[i].CountryId < result.value[j].CountryId
+		})
+	}
+	result.known = true
+	result.source = mergeSources(sources)
+	return result, nil
+}
+
+// unanswered source of value no provider knows, records that every provider was asked and answered nothing
+func unanswered[P any](providers []weighted[P]) models.Source {
+	if len(providers) == 0 {
+		return models.Source{}
+	}
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.name
+	}
+	return newSource(strings.Join(names, "+"), 0)
+}
+
+func contribution(attribute, provider string, weight float64, source models.Source, value string, probability *float64) models.Contribution {
+	return models.Contribution{
+		Attribute:   attribute,
+		Provider:    provider,
+		Value:       value,
+		Probability: probability,
+		Weight:      weight,
+		Count:       source.Count,
+		Country:     source.Country,
+	}
+}
+
+// mergeSources describes fused value: providers are joined with "+", counts are summed, the latest time is kept
+func mergeSources(sources []models.Source) models.Source {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	var merged models.Source
+	names := make([]string, len(sources))
+	for i, src := range sources {
+		names[i] = src.Provider
+		merged.Count += src.Count
+		if src.EnrichedAt != nil && (merged.EnrichedAt == nil || src.EnrichedAt.After(*merged.EnrichedAt)) {
+			merged.EnrichedAt = src.EnrichedAt
+		}
+		if merged.Country == "" {
+			merged.Country = src.Country
+		}
+	}
+	merged.Provider = strings.Join(names, "+")
+	return merged
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nutochk/ef-test/internal/models"
+	logger2 "github.com/nutochk/ef-test/pkg/logger"
+)
+
+type fakeProvider struct {
+	ageValue    *ageEstimate
+	genderValue *genderEstimate
+	countries   *nationalityEstimate
+	err         error
+}
+
+func (f fakeProvider) age(context.Context, *models.Person, string) (*ageEstimate, error) {
+	return f.ageValue, f.err
+}
+
+func (f fakeProvider) gender(context.Context, *models.Person, string) (*genderEstimate, error) {
+	return f.genderValue, f.err
+}
+
+func (f fakeProvider) nationality(context.Context, *models.Person) (*nationalityEstimate, error) {
+	return f.countries, f.err
+}
+
+func TestResolveAge(t *testing.T) {
+	log, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := &service{logger: *log}
+	person := &models.Person{Name: "Dmitriy"}
+
+	svc.ageProviders = []weighted[ageProvider]{
+		{name: "a", weight: 3, value: fakeProvider{ageValue: &ageEstimate{age: 40, source: models.Source{Provider: "a", Count: 10}}}},
+		{name: "b", weight: 1, value: fakeProvider{ageValue: &ageEstimate{age: 20, source: models.Source{Provider: "b", Count: 5}}}},
+		{name: "c", weight: 1, value: fakeProvider{err: errors.New("unavailable")}},
+	}
+	age, err := svc.resolveAge(context.Background(), person, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !age.known || age.value != 35 || age.source.Provider != "a+b" || age.source.Count != 15 || len(age.contributions) != 2 {
+		t.Errorf("unexpected fused age %+v", age)
+	}
+
+	svc.ageProviders = svc.ageProviders[2:]
+	if _, err = svc.resolveAge(context.Background(), person, ""); err == nil {
+		t.Error("expected error when every provider failed")
+	}
+
+	svc.ageProviders = []weighted[ageProvider]{{name: "a", weight: 1, value: fakeProvider{}}}
+	if age, err = svc.resolveAge(context.Background(), person, ""); err != nil || age.known {
+		t.Errorf("expected unknown age, got %+v, %v", age, err)
+	}
+	if age.source.Provider != "a" || age.source.Count != 0 || age.source.EnrichedAt == nil {
+		t.Errorf("expected unknown age to record the asked provider, got %+v", age.source)
+	}
+}
+
+func TestResolveGenderProviderFailed(t *testing.T) {
+	log, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := &service{logger: *log}
+	person := &models.Person{Name: "Sasha"}
+	failed := weighted[genderProvider]{name: "genderize", weight: 1, value: fakeProvider{err: errors.New("unavailable")}}
+
+	svc.genderProviders = []weighted[genderProvider]{failed, {name: "rules", weight: 1, value: fakeProvider{}}}
+	if gender, err := svc.resolveGender(context.Background(), person, ""); err == nil {
+		t.Errorf("expected error when a provider failed and the others do not know gender, got %+v", gender)
+	}
+
+	estimate := &genderEstimate{opinion: genderOpinion{gender: male, probability: 0.9}, source: models.Source{Provider: "rules"}}
+	svc.genderProviders = []weighted[genderProvider]{failed, {name: "rules", weight: 1, value: fakeProvider{genderValue: estimate}}}
+	if gender, err := svc.resolveGender(context.Background(), person, ""); err != nil || !gender.known || gender.value.gender != male {
+		t.Errorf("expected gender of the working provider, got %+v, %v", gender, err)
+	}
+}
+
+func TestResolveNationality(t *testing.T) {
+	log, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := &service{logger: *log}
+	svc.nationalityProviders = []weighted[nationalityProvider]{
+		{name: "a", weight: 1, value: fakeProvider{countries: &nationalityEstimate{countries: []models.Country{{CountryId: "RU", Probability: 0.6}, {CountryId: "UA", Probability: 0.4}}}}},
+		{name: "b", weight: 1, value: fakeProvider{countries: &nationalityEstimate{countries: []models.Country{{CountryId: "UA", Probability: 1}}}}},
+	}
+	nationality, err := svc.resolveNationality(context.Background(), &models.Person{Name: "Olena"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.Country{{CountryId: "UA", Probability: 0.7}, {CountryId: "RU", Probability: 0.3}}
+	if len(nationality.value) != len(expected) || nationality.value[0] != expected[0] || nationality.value[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, nationality.value)
+	}
+}
+
+func TestSelectProviders(t *testing.T) {
+	available := map[string]any{agify: agifyProvider{}, genderize: genderizeProvider{}}
+	selected := selectProviders[ageProvider]([]string{agify, genderize}, available, map[string]float64{agify: 2})
+	if len(selected) != 1 || selected[0].name != agify || selected[0].weight != 2 {
+		t.Errorf("unexpected providers %+v", selected)
+	}
+	selected = selectProviders[ageProvider]([]string{agify}, available, nil)
+	if len(selected) != 1 || selected[0].weight != 1 {
+		t.Errorf("missing weight must default to 1, got %+v", selected)
+	}
+}
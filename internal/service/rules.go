@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/nutochk/ef-test/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	localRules = "rules"
+
+	male   = "male"
+	female = "female"
+
+	PartPatronymic = "patronymic"
+	PartSurname    = "surname"
+)
+
+// SuffixRule gender given by ending of patronymic or surname
+type SuffixRule struct {
+	Part        string  `yaml:"part"`
+	Suffix      string  `yaml:"suffix"`
+	Gender      string  `yaml:"gender"`
+	Probability float64 `yaml:"probability"`
+}
+
+// GenderRules suffix rules by language
+type GenderRules map[string][]SuffixRule
+
+// DefaultGenderRules rules for East Slavic patronymics and surnames in Cyrillic and Latin spelling
+var DefaultGenderRules = GenderRules{
+	"ru": {
+		{PartPatronymic, "ович", male, 0.99}, {PartPatronymic, "евич", male, 0.99}, {PartPatronymic, "ич", male, 0.97},
+		{PartPatronymic, "овна", female, 0.99}, {PartPatronymic, "евна", female, 0.99}, {PartPatronymic, "ична", female, 0.99},
+		{PartPatronymic, "ovich", male, 0.99}, {PartPatronymic, "evich", male, 0.99},
+		{PartPatronymic, "ovna", female, 0.99}, {PartPatronymic, "evna", female, 0.99}, {PartPatronymic, "ichna", female, 0.99},
+		{PartSurname, "ов", male, 0.9}, {PartSurname, "ев", male, 0.9}, {PartSurname, "ин", male, 0.85},
+		{PartSurname, "ский", male, 0.95}, {PartSurname, "цкий", male, 0.95},
+		{PartSurname, "ова", female, 0.95}, {PartSurname, "ева", female, 0.95}, {PartSurname, "ина", female, 0.9},
+		{PartSurname, "ская", female, 0.97}, {PartSurname, "цкая", female, 0.97},
+		{PartSurname, "ov", male, 0.9}, {PartSurname, "ev", male, 0.9}, {PartSurname, "sky", male, 0.9}, {PartSurname, "skiy", male, 0.95},
+		{PartSurname, "ova", female, 0.95}, {PartSurname, "eva", female, 0.95}, {PartSurname, "skaya", female, 0.97},
+	},
+	"uk": {
+		{PartPatronymic, "ович", male, 0.99}, {PartPatronymic, "йович", male, 0.99},
+		{PartPatronymic, "івна", female, 0.99}, {PartPatronymic, "ївна", female, 0.99},
+		{PartPatronymic, "ovych", male, 0.99}, {PartPatronymic, "ivna", female, 0.99}, {PartPatronymic, "yivna", female, 0.99},
+		{PartSurname, "ський", male, 0.95}, {PartSurname, "цький", male, 0.95},
+		{PartSurname, "ська", female, 0.97}, {PartSurname, "цька", female, 0.97},
+		{PartSurname, "skyi", male, 0.95}, {PartSurname, "ska", female, 0.9},
+	},
+	"be": {
+		{PartPatronymic, "авіч", male, 0.99}, {PartPatronymic, "евіч", male, 0.99},
+		{PartPatronymic, "аўна", female, 0.99}, {PartPatronymic, "еўна", female, 0.99},
+		{PartPatronymic, "avich", male, 0.99}, {PartPatronymic, "auna", female, 0.99},
+	},
+}
+
+// LoadGenderRules reads rules from YAML file, defaults are returned when path is empty
+func LoadGenderRules(path string) (GenderRules, error) {
+	if path == "" {
+		return DefaultGenderRules, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gender rules: %w", err)
+	}
+	var rules GenderRules
+	if err = yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse gender rules: %w", err)
+	}
+	for lang, list := range rules {
+		for _, r := range list {
+			if (r.Part != PartPatronymic && r.Part != PartSurname) || (r.Gender != male && r.Gender != female) ||
+				r.Suffix == "" || r.Probability <= 0 || r.Probability > 1 {
+				return nil, fmt.Errorf("invalid gender rule for %s: %+v", lang, r)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// genderOpinion gender with probability given by a provider
+type genderOpinion struct {
+	gender      string
+	probability float64
+}
+
+// inferGender finds the longest matching suffix among rules of the given languages,
+// patronymic rules take precedence over surname ones
+func inferGender(rules GenderRules, languages []string, p *models.Person) (genderOpinion, bool) {
+	for _, part := range []string{PartPatronymic, PartSurname} {
+		value := p.Patronymic
+		if part == PartSurname {
+			value = p.Surname
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		var best *SuffixRule
+		for _, lang := range languages {
+			for i, r := range rules[lang] {
+				if r.Part == part && strings.HasSuffix(value, r.Suffix) && (best == nil || len(r.Suffix) > len(best.Suffix)) {
+					best = &rules[lang][i]
+				}
+			}
+		}
+		if best != nil {
+			return genderOpinion{gender: best.Gender, probability: best.Probability}, true
+		}
+	}
+	return genderOpinion{}, false
+}
+
+// fuseGender combines independent opinions by summing their log-odds multiplied by weights,
+// with equal weights it is the Bayes rule. Conflict reports that opinions disagree.
+func fuseGender(opinions []genderOpinion, weights []float64) (fused genderOpinion, conflict bool) {
+	var logOdds float64
+	for i, o := range opinions {
+		pm := maleProbability(o)
+		logOdds += weights[i] * math.Log(pm/(1-pm))
+		conflict = conflict || o.gender != opinions[0].gender
+	}
+	pm := 1 / (1 + math.Exp(-logOdds))
+	if pm >= 0.5 {
+		fused = genderOpinion{gender: male, probability: pm}
+	} else {
+		fused = genderOpinion{gender: female, probability: 1 - pm}
+	}
+	fused.probability = math.Round(fused.probability*1000) / 1000
+	return fused, conflict
+}
+
+// rulesProvider infers gender by local suffix rules
+type rulesProvider struct {
+	s *service
+}
+
+func (r rulesProvider) gender(_ context.Context, p *models.Person, _ string) (*genderEstimate, error) {
+	opinion, ok := inferGender(r.s.rules, r.s.cfg.GenderLanguages, p)
+	if !ok {
+		return nil, nil
+	}
+	return &genderEstimate{opinion: opinion, source: newSource(localRules, 0)}, nil
+}
+
+// maleProbability converts opinion to probability of male, bounded away from 0 and 1 so that opinions can be combined
+func maleProbability(o genderOpinion) float64 {
+	p := math.Min(math.Max(o.probability, 0.001), 0.999)
+	if o.gender == male {
+		return p
+	}
+	return 1 - p
+}
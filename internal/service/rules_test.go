@@ -0,0 +1,83 @@
+package service
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nutochk/ef-test/internal/models"
+)
+
+func TestInferGender(t *testing.T) {
+	languages := []string{"ru", "uk", "be"}
+	tests := []struct {
+		person models.Person
+		gender string
+		known  bool
+	}{
+		{models.Person{Surname: "Иванов", Patronymic: "Петрович"}, male, true},
+		{models.Person{Surname: "Иванова", Patronymic: "Петровна"}, female, true},
+		{models.Person{Surname: "Ushakov", Patronymic: "Vasilevna"}, female, true},
+		{models.Person{Surname: "Ильина"}, female, true},
+		{models.Person{Surname: "Шевченко", Patronymic: "Григорович"}, male, true},
+		{models.Person{Surname: "Коваленко", Patronymic: "Іванівна"}, female, true},
+		{models.Person{Surname: "Lukashevich"}, "", false},
+		{models.Person{Surname: "Smith"}, "", false},
+	}
+	for _, tt := range tests {
+		opinion, known := inferGender(DefaultGenderRules, languages, &tt.person)
+		if known != tt.known || opinion.gender != tt.gender {
+			t.Errorf("%+v: expected %q/%v, got %q/%v", tt.person, tt.gender, tt.known, opinion.gender, known)
+		}
+	}
+
+	if _, known := inferGender(DefaultGenderRules, []string{"be"}, &models.Person{Surname: "Иванов"}); known {
+		t.Error("rules of disabled languages must not apply")
+	}
+}
+
+func TestFuseGender(t *testing.T) {
+	equal := []float64{1, 1}
+	fused, conflict := fuseGender([]genderOpinion{{male, 0.8}, {male, 0.9}}, equal)
+	if conflict || fused.gender != male || fused.probability <= 0.9 {
+		t.Errorf("agreement must boost probability, got %+v conflict %v", fused, conflict)
+	}
+
+	fused, conflict = fuseGender([]genderOpinion{{male, 0.6}, {female, 0.99}}, equal)
+	if !conflict || fused.gender != female || fused.probability >= 0.99 {
+		t.Errorf("conflict must be flagged and lower probability, got %+v conflict %v", fused, conflict)
+	}
+
+	fused, _ = fuseGender([]genderOpinion{{male, 1}, {female, 1}}, equal)
+	if math.IsNaN(fused.probability) {
+		t.Errorf("certain opposite opinions must not produce NaN")
+	}
+
+	fused, _ = fuseGender([]genderOpinion{{male, 0.9}, {female, 0.9}}, []float64{3, 1})
+	if fused.gender != male {
+		t.Errorf("heavier provider must win, got %+v", fused)
+	}
+}
+
+func TestLoadGenderRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	data := "pl:\n  - {part: surname, suffix: ska, gender: female, probability: 0.95}\n  - {part: surname, suffix: ski, gender: male, probability: 0.95}\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadGenderRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opinion, known := inferGender(rules, []string{"pl"}, &models.Person{Surname: "Kowalski"}); !known || opinion.gender != male {
+		t.Errorf("expected male by loaded rules, got %+v %v", opinion, known)
+	}
+
+	if err = os.WriteFile(path, []byte("pl:\n  - {part: name, suffix: a, gender: female, probability: 2}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadGenderRules(path); err == nil {
+		t.Error("expected error for invalid rule")
+	}
+}
@@ -1,107 +1,330 @@
 package service
 
 import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
 	"github.com/nutochk/ef-test/internal/dto"
 	"github.com/nutochk/ef-test/internal/models"
 	"github.com/nutochk/ef-test/internal/repository"
 	"github.com/nutochk/ef-test/pkg/logger"
+	"github.com/nutochk/ef-test/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/nutochk/ef-test/internal/service")
+
 type Service interface {
-	Create(p *models.Person) (*dto.PersonInfo, error)
-	Update(id int, i *models.Person) (*models.PersonInfo, error)
-	Delete(id int) error
-	GetById(id int) (*models.PersonInfo, error)
-	GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination) (*dto.PaginatedResponse, error)
+	Create(ctx context.Context, p *models.Person) (*dto.PersonInfo, error)
+	Preview(ctx context.Context, p *models.Person) (*models.PersonInfo, error)
+	Update(ctx context.Context, id int, i *models.Person) (*models.PersonInfo, error)
+	Delete(ctx context.Context, id int) error
+	GetById(ctx context.Context, id int) (*models.PersonInfo, error)
+	Explain(ctx context.Context, id int) ([]models.Contribution, error)
+	GetPeople(ctx context.Context, filters *dto.PersonFilter, pagination *dto.Pagination) (*dto.PaginatedResponse, error)
+	GetStats(ctx context.Context, filters *dto.PersonFilter, query *dto.StatsQuery) (*dto.PeopleStats, error)
+}
+
+// Config enrichment settings
+type Config struct {
+	CacheTTL  time.Duration `yaml:"ENRICHMENT_CACHE_TTL" env:"ENRICHMENT_CACHE_TTL" env-default:"1h"`
+	CacheSize int           `yaml:"ENRICHMENT_CACHE_SIZE" env:"ENRICHMENT_CACHE_SIZE" env-default:"10000"`
+	Timeout   time.Duration `yaml:"ENRICHMENT_TIMEOUT" env:"ENRICHMENT_TIMEOUT" env-default:"10s"`
+	// base URLs of remote providers, e.g. of cmd/fakeproviders for local development
+	AgifyURL       string `yaml:"ENRICHMENT_AGIFY_URL" env:"ENRICHMENT_AGIFY_URL" env-default:"https://api.agify.io"`
+	GenderizeURL   string `yaml:"ENRICHMENT_GENDERIZE_URL" env:"ENRICHMENT_GENDERIZE_URL" env-default:"https://api.genderize.io"`
+	NationalizeURL string `yaml:"ENRICHMENT_NATIONALIZE_URL" env:"ENRICHMENT_NATIONALIZE_URL" env-default:"https://api.nationalize.io"`
+	// Localization how country for age and gender requests is chosen: auto, hint or off
+	Localization string `yaml:"ENRICHMENT_LOCALIZATION" env:"ENRICHMENT_LOCALIZATION" env-default:"auto"`
+	// CountryThreshold minimal probability of the top nationality to localize by it
+	CountryThreshold float64 `yaml:"ENRICHMENT_COUNTRY_THRESHOLD" env:"ENRICHMENT_COUNTRY_THRESHOLD" env-default:"0.3"`
+	// LocalGender infers gender from patronymic and surname endings and fuses it with genderize
+	LocalGender     bool     `yaml:"ENRICHMENT_LOCAL_GENDER" env:"ENRICHMENT_LOCAL_GENDER" env-default:"false"`
+	GenderRulesFile string   `yaml:"ENRICHMENT_GENDER_RULES_FILE" env:"ENRICHMENT_GENDER_RULES_FILE"`
+	GenderLanguages []string `yaml:"ENRICHMENT_GENDER_LANGUAGES" env:"ENRICHMENT_GENDER_LANGUAGES" env-separator:"," env-default:"ru,uk,be"`
+	// providers of every attribute, their answers are fused with ProviderWeights (1 by default)
+	AgeProviders         []string           `yaml:"ENRICHMENT_AGE_PROVIDERS" env:"ENRICHMENT_AGE_PROVIDERS" env-separator:"," env-default:"agify"`
+	GenderProviders      []string           `yaml:"ENRICHMENT_GENDER_PROVIDERS" env:"ENRICHMENT_GENDER_PROVIDERS" env-separator:"," env-default:"genderize"`
+	NationalityProviders []string           `yaml:"ENRICHMENT_NATIONALITY_PROVIDERS" env:"ENRICHMENT_NATIONALITY_PROVIDERS" env-separator:"," env-default:"nationalize"`
+	ProviderWeights      map[string]float64 `yaml:"ENRICHMENT_PROVIDER_WEIGHTS" env:"ENRICHMENT_PROVIDER_WEIGHTS"`
+	// DatasetFile CSV or JSON name statistics for the dataset provider, checked for changes every DatasetReloadInterval
+	DatasetFile           string        `yaml:"ENRICHMENT_DATASET_FILE" env:"ENRICHMENT_DATASET_FILE"`
+	DatasetReloadInterval time.Duration `yaml:"ENRICHMENT_DATASET_RELOAD_INTERVAL" env:"ENRICHMENT_DATASET_RELOAD_INTERVAL" env-default:"1m"`
+	// NameOrders orders of full_name parts by locale, see DefaultNameOrders
+	NameOrders            map[string]string `yaml:"ENRICHMENT_NAME_ORDERS" env:"ENRICHMENT_NAME_ORDERS" env-default:"ru:SNP|NPS|SN|NS,uk:SNP|NPS|SN|NS,be:SNP|NPS|SN|NS,en:NS|NPS"`
+	FullNameMinConfidence float64           `yaml:"ENRICHMENT_FULL_NAME_MIN_CONFIDENCE" env:"ENRICHMENT_FULL_NAME_MIN_CONFIDENCE" env-default:"0.7"`
+}
+
+type Option func(*service)
+
+// WithGenderRules replaces default rules of local gender inference
+func WithGenderRules(rules GenderRules) Option {
+	return func(s *service) {
+		s.rules = rules
+	}
+}
+
+// WithDataset enables the dataset provider
+func WithDataset(dataset *Dataset) Option {
+	return func(s *service) {
+		s.dataset = dataset
+	}
 }
 
 type service struct {
-	repo   repository.Repository
-	logger logger.Logger
+	cfg           Config
+	repo          repository.Repository
+	logger        logger.Logger
+	client        *http.Client
+	ages          *cache[enriched[models.AgeResponse]]
+	genders       *cache[enriched[models.GenderResponse]]
+	nationalities *cache[enriched[models.NationalityResponse]]
+	rules         GenderRules
+	dataset       *Dataset
+	nameOrders    map[string][]string
+
+	ageProviders         []weighted[ageProvider]
+	genderProviders      []weighted[genderProvider]
+	nationalityProviders []weighted[nationalityProvider]
+}
+
+func New(repo repository.Repository, log logger.Logger, cfg Config, opts ...Option) *service {
+	if len(cfg.AgeProviders) == 0 {
+		cfg.AgeProviders = []string{agify}
+	}
+	if len(cfg.GenderProviders) == 0 {
+		cfg.GenderProviders = []string{genderize}
+	}
+	if len(cfg.NationalityProviders) == 0 {
+		cfg.NationalityProviders = []string{nationalize}
+	}
+	if len(cfg.NameOrders) == 0 {
+		cfg.NameOrders = DefaultNameOrders
+	}
+	s := &service{
+		cfg:           cfg,
+		repo:          repo,
+		logger:        log,
+		client:        &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport), Timeout: cfg.Timeout},
+		ages:          newCache[enriched[models.AgeResponse]](agify, cfg.CacheTTL, cfg.CacheSize),
+		genders:       newCache[enriched[models.GenderResponse]](genderize, cfg.CacheTTL, cfg.CacheSize),
+		nationalities: newCache[enriched[models.NationalityResponse]](nationalize, cfg.CacheTTL, cfg.CacheSize),
+		rules:         DefaultGenderRules,
+	}
+	// invalid orders are reported by config validation and skipped here
+	s.nameOrders, _ = ParseNameOrders(cfg.NameOrders)
+	for _, opt := range opts {
+		opt(s)
+	}
+	available := map[string]any{
+		agify:       agifyProvider{s},
+		genderize:   genderizeProvider{s},
+		nationalize: nationalizeProvider{s},
+		localRules:  rulesProvider{s},
+	}
+	if s.dataset != nil {
+		available[datasetProvider] = s.dataset
+	}
+	genderProviders := cfg.GenderProviders
+	if cfg.LocalGender && !slices.Contains(genderProviders, localRules) {
+		genderProviders = append(slices.Clone(genderProviders), localRules)
+	}
+	s.ageProviders = selectProviders[ageProvider](cfg.AgeProviders, available, cfg.ProviderWeights)
+	s.genderProviders = selectProviders[genderProvider](genderProviders, available, cfg.ProviderWeights)
+	s.nationalityProviders = selectProviders[nationalityProvider](cfg.NationalityProviders, available, cfg.ProviderWeights)
+	return s
+}
+
+// log returns request-scoped logger carried by ctx
+func (s *service) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, &s.logger)
 }
 
-func New(repo repository.Repository, log logger.Logger) *service {
-	return &service{repo: repo, logger: log}
+func (s *service) Create(ctx context.Context, p *models.Person) (*dto.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "service.Create")
+	defer span.End()
+
+	log := s.log(ctx)
+	log.Debug("create method in service")
+	var parsed *models.ParsedName
+	if p.FullName != "" {
+		var err error
+		if parsed, err = s.parseFullName(p); err != nil {
+			log.Info("failed to parse full name", zap.Error(err))
+			tracing.Error(span, err)
+			return nil, err
+		}
+		p.Name, p.Surname, p.Patronymic = parsed.Name, parsed.Surname, parsed.Patronymic
+	}
+	pi, err := s.Enrich(ctx, p)
+	if err != nil {
+		tracing.Error(span, err)
+		return nil, err
+	}
+	id, err := s.repo.Create(ctx, pi)
+	if err != nil {
+		log.Error("failed to create in repository", zap.Error(err))
+		tracing.Error(span, err)
+		return nil, err
+	}
+	log.Info("person created", zap.Int("person_id", id))
+	person := dto.PersonInfo{
+		Id:                id,
+		Name:              pi.Name,
+		Surname:           pi.Surname,
+		Patronymic:        pi.Patronymic,
+		Age:               pi.Age,
+		Gender:            pi.Gender,
+		GenderProbability: pi.GenderProbability,
+		Nationality:       pi.Nationality,
+		Provenance:        pi.Provenance,
+		CreatedAt:         pi.CreatedAt,
+		UpdatedAt:         pi.UpdatedAt,
+		ParsedName:        parsed,
+	}
+	return &person, nil
 }
 
-func (s *service) Create(p *models.Person) (*dto.PersonInfo, error) {
-	s.logger.Debug("create method in service")
-	age, err := getAge(p.Name)
+// Preview enriches person like Create without storing the result
+func (s *service) Preview(ctx context.Context, p *models.Person) (*models.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "service.Preview")
+	defer span.End()
+
+	s.log(ctx).Debug("preview method in service")
+	pi, err := s.Enrich(ctx, p)
 	if err != nil {
-		s.logger.Error("failed to get age in create method", zap.Error(err))
+		tracing.Error(span, err)
 		return nil, err
 	}
-	gender, prob, err := getGender(p.Name)
+	return pi, nil
+}
+
+// Enrich determines age, gender and nationality of person by name with configured providers,
+// failure of nationality providers is tolerated and leaves nationality empty
+func (s *service) Enrich(ctx context.Context, p *models.Person) (*models.PersonInfo, error) {
+	log := s.log(ctx)
+	nationality, err := s.resolveNationality(ctx, p)
 	if err != nil {
-		s.logger.Error("failed to get gender in create method", zap.Error(err))
+		log.Error("failed to get countries", zap.Error(err))
+	}
+	country, localization := s.localization(p.CountryHint, nationality.value)
+
+	age, err := s.resolveAge(ctx, p, country)
+	if err != nil {
+		log.Error("failed to get age", zap.Error(err))
 		return nil, err
 	}
-	countries, err := getCountries(p.Name)
+	gender, err := s.resolveGender(ctx, p, country)
 	if err != nil {
-		s.logger.Error("failed to get countries in create method", zap.Error(err))
+		log.Error("failed to get gender", zap.Error(err))
+		return nil, err
+	}
+	if age.source.Country == "" && gender.source.Country == "" {
+		localization = ""
 	}
 	var pi models.PersonInfo
 	pi.Name = p.Name
 	pi.Surname = p.Surname
 	pi.Patronymic = p.Patronymic
-	pi.Age = age
-	pi.Gender = gender
-	pi.GenderProbability = prob
-	pi.Nationality = countries
-	id, err := s.repo.Create(&pi)
-	if err != nil {
-		s.logger.Error("failed to create in repository", zap.Error(err))
-		return nil, err
+	if age.known {
+		pi.Age = &age.value
 	}
-	person := dto.PersonInfo{
-		Id:                id,
-		Name:              p.Name,
-		Surname:           p.Surname,
-		Patronymic:        p.Patronymic,
-		Age:               age,
-		Gender:            gender,
-		GenderProbability: prob,
-		Nationality:       countries,
+	if gender.known {
+		pi.Gender = &gender.value.gender
+		pi.GenderProbability = &gender.value.probability
 	}
-	return &person, nil
+	pi.Nationality = nationality.value
+	pi.Provenance = models.Provenance{
+		Age:            age.source,
+		Gender:         gender.source,
+		Nationality:    nationality.source,
+		Localization:   localization,
+		CountryHint:    p.CountryHint,
+		GenderConflict: gender.conflict,
+	}
+	pi.Contributions = slices.Concat(age.contributions, gender.contributions, nationality.contributions)
+	return &pi, nil
 }
 
-func (s *service) Update(id int, p *models.Person) (*models.PersonInfo, error) {
-	s.logger.Debug("update method in service")
-	pi, err := s.repo.Update(id, p)
+func (s *service) Update(ctx context.Context, id int, p *models.Person) (*models.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "service.Update")
+	defer span.End()
+
+	log := s.log(ctx).With(zap.Int("person_id", id))
+	ctx = logger.WithContext(ctx, log)
+	log.Debug("update method in service")
+	pi, err := s.repo.Update(ctx, id, p)
 	if err != nil {
-		s.logger.Error("failed to update in repository", zap.Error(err))
+		log.Error("failed to update in repository", zap.Error(err))
+		tracing.Error(span, err)
 		return nil, err
 	}
 	return pi, nil
 }
 
-func (s *service) Delete(id int) error {
-	s.logger.Debug("delete method in service")
-	_, err := s.repo.Delete(id)
+func (s *service) Delete(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "service.Delete")
+	defer span.End()
+
+	log := s.log(ctx).With(zap.Int("person_id", id))
+	ctx = logger.WithContext(ctx, log)
+	log.Debug("delete method in service")
+	_, err := s.repo.Delete(ctx, id)
 	if err != nil {
-		s.logger.Error("failed to delete in repository", zap.Error(err))
+		log.Error("failed to delete in repository", zap.Error(err))
+		tracing.Error(span, err)
 		return err
 	}
 	return nil
 }
 
-func (s *service) GetById(id int) (*models.PersonInfo, error) {
-	s.logger.Debug("get by id method in service")
-	pi, err := s.repo.GetById(id)
+func (s *service) GetById(ctx context.Context, id int) (*models.PersonInfo, error) {
+	ctx, span := tracer.Start(ctx, "service.GetById")
+	defer span.End()
+
+	log := s.log(ctx).With(zap.Int("person_id", id))
+	ctx = logger.WithContext(ctx, log)
+	log.Debug("get by id method in service")
+	pi, err := s.repo.GetById(ctx, id)
 	if err != nil {
-		s.logger.Error("failed to get by id in repository", zap.Error(err))
+		log.Error("failed to get by id in repository", zap.Error(err))
+		tracing.Error(span, err)
 		return nil, err
 	}
 	return pi, nil
 }
 
-func (s *service) GetPeople(filters *dto.PersonFilter, pagination *dto.Pagination) (*dto.PaginatedResponse, error) {
-	s.logger.Debug("get people method in service")
-	people, total, err := s.repo.GetPeople(filters, pagination)
+// Explain returns answers of providers the current attributes of person were fused from
+func (s *service) Explain(ctx context.Context, id int) ([]models.Contribution, error) {
+	ctx, span := tracer.Start(ctx, "service.Explain")
+	defer span.End()
+
+	log := s.log(ctx).With(zap.Int("person_id", id))
+	ctx = logger.WithContext(ctx, log)
+	log.Debug("explain method in service")
+	contributions, err := s.repo.GetContributions(ctx, id)
 	if err != nil {
-		s.logger.Error("failed to get people in repository", zap.Error(err))
+		log.Error("failed to get contributions in repository", zap.Error(err))
+		tracing.Error(span, err)
+		return nil, err
+	}
+	return contributions, nil
+}
+
+func (s *service) GetPeople(ctx context.Context, filters *dto.PersonFilter, pagination *dto.Pagination) (*dto.PaginatedResponse, error) {
+	ctx, span := tracer.Start(ctx, "service.GetPeople")
+	defer span.End()
+
+	log := s.log(ctx)
+	log.Debug("get people method in service")
+	people, total, err := s.repo.GetPeople(ctx, filters, pagination)
+	if err != nil {
+		log.Error("failed to get people in repository", zap.Error(err))
+		tracing.Error(span, err)
 		return nil, err
 	}
 	response := dto.PaginatedResponse{
@@ -114,3 +337,18 @@ func (s *service) GetPeople(filters *dto.PersonFilter, pagination *dto.Paginatio
 	}
 	return &response, nil
 }
+
+func (s *service) GetStats(ctx context.Context, filters *dto.PersonFilter, query *dto.StatsQuery) (*dto.PeopleStats, error) {
+	ctx, span := tracer.Start(ctx, "service.GetStats")
+	defer span.End()
+
+	log := s.log(ctx)
+	log.Debug("get stats method in service")
+	stats, err := s.repo.GetStats(ctx, filters, query)
+	if err != nil {
+		log.Error("failed to get stats in repository", zap.Error(err))
+		tracing.Error(span, err)
+		return nil, err
+	}
+	return stats, nil
+}
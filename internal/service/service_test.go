@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/nutochk/ef-test/internal/dto"
+	"github.com/nutochk/ef-test/internal/fakeproviders"
 	"github.com/nutochk/ef-test/internal/models"
 	"github.com/nutochk/ef-test/internal/repository"
 	logger2 "github.com/nutochk/ef-test/pkg/logger"
@@ -15,23 +18,56 @@ func TestCreate(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockRepository(ctrl)
-	logger, _ := logger2.New()
-	svc := New(mockRepo, *logger)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	providers := httptest.NewServer(fakeproviders.New(fakeproviders.Config{Seed: 1}, logger).Handler())
+	defer providers.Close()
+	svc := New(mockRepo, *logger, Config{
+		AgifyURL:       providers.URL + "/agify",
+		GenderizeURL:   providers.URL + "/genderize",
+		NationalizeURL: providers.URL + "/nationalize",
+	})
 
 	person := &models.Person{Name: "John", Surname: "Doe", Patronymic: "Smith"}
 	expectedPersonInfo := &dto.PersonInfo{Id: 1, Name: "John", Surname: "Doe", Patronymic: "Smith"}
 
-	mockRepo.EXPECT().Create(gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(1, nil)
 
-	result, err := svc.Create(person)
+	result, err := svc.Create(context.Background(), person)
 
 	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+		t.Fatalf("Expected no error, got %v", err)
 	}
 
 	if result.Id != expectedPersonInfo.Id {
 		t.Errorf("Expected Id %d, got %d", expectedPersonInfo.Id, result.Id)
 	}
+	if result.Age == nil || result.Gender == nil || len(result.Nationality) == 0 {
+		t.Errorf("Expected enriched person, got %+v", result)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no repository calls are expected
+	mockRepo := repository.NewMockRepository(ctrl)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	providers := httptest.NewServer(fakeproviders.New(fakeproviders.Config{Seed: 1}, logger).Handler())
+	defer providers.Close()
+	svc := New(mockRepo, *logger, Config{
+		AgifyURL:       providers.URL + "/agify",
+		GenderizeURL:   providers.URL + "/genderize",
+		NationalizeURL: providers.URL + "/nationalize",
+	})
+
+	result, err := svc.Preview(context.Background(), &models.Person{Name: "John"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Age == nil || len(result.Contributions) == 0 {
+		t.Errorf("Expected enriched person with contributions, got %+v", result)
+	}
 }
 
 func TestUpdate(t *testing.T) {
@@ -39,15 +75,15 @@ func TestUpdate(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockRepository(ctrl)
-	logger, _ := logger2.New()
-	svc := New(mockRepo, *logger)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := New(mockRepo, *logger, Config{})
 
 	person := &models.Person{Name: "John", Surname: "Doe", Patronymic: "Smith"}
 	updatedInfo := &models.PersonInfo{}
 
-	mockRepo.EXPECT().Update(1, person).Return(updatedInfo, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), 1, person).Return(updatedInfo, nil)
 
-	result, err := svc.Update(1, person)
+	result, err := svc.Update(context.Background(), 1, person)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -63,12 +99,12 @@ func TestDelete(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockRepository(ctrl)
-	logger, _ := logger2.New()
-	svc := New(mockRepo, *logger)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := New(mockRepo, *logger, Config{})
 
-	mockRepo.EXPECT().Delete(1).Return(true, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), 1).Return(true, nil)
 
-	err := svc.Delete(1)
+	err := svc.Delete(context.Background(), 1)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -80,14 +116,14 @@ func TestGetById(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockRepository(ctrl)
-	logger, _ := logger2.New()
-	svc := New(mockRepo, *logger)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := New(mockRepo, *logger, Config{})
 
 	expectedPersonInfo := &models.PersonInfo{Name: "John", Surname: "Doe"}
 
-	mockRepo.EXPECT().GetById(1).Return(expectedPersonInfo, nil)
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(expectedPersonInfo, nil)
 
-	result, err := svc.GetById(1)
+	result, err := svc.GetById(context.Background(), 1)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -103,17 +139,17 @@ func TestGetPeople(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockRepository(ctrl)
-	logger, _ := logger2.New()
-	svc := New(mockRepo, *logger)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := New(mockRepo, *logger, Config{})
 
 	filters := &dto.PersonFilter{}
 	pagination := &dto.Pagination{Page: 1, PerPage: 10}
 	people := &[]dto.PersonInfo{{Id: 1, Name: "John", Surname: "Doe"}}
 	total := 1
 
-	mockRepo.EXPECT().GetPeople(filters, pagination).Return(people, total, nil)
+	mockRepo.EXPECT().GetPeople(gomock.Any(), filters, pagination).Return(people, total, nil)
 
-	result, err := svc.GetPeople(filters, pagination)
+	result, err := svc.GetPeople(context.Background(), filters, pagination)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -123,3 +159,53 @@ func TestGetPeople(t *testing.T) {
 		t.Errorf("Expected people , got %v", result)
 	}
 }
+
+func TestGetStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockRepository(ctrl)
+	logger, _ := logger2.New(logger2.Config{Level: "debug"})
+	svc := New(mockRepo, *logger, Config{})
+
+	filters := &dto.PersonFilter{Gender: "male"}
+	query := &dto.StatsQuery{BucketSize: 10, Top: 5}
+	stats := &dto.PeopleStats{Total: 2, Genders: []dto.GenderStats{{Gender: "male", Count: 2, AvgProbability: 0.9}}}
+
+	mockRepo.EXPECT().GetStats(gomock.Any(), filters, query).Return(stats, nil)
+
+	result, err := svc.GetStats(context.Background(), filters, query)
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if result != stats {
+		t.Errorf("Expected %v, got %v", stats, result)
+	}
+}
+
+func TestLocalization(t *testing.T) {
+	countries := []models.Country{{CountryId: "RU", Probability: 0.2}, {CountryId: "UA", Probability: 0.5}}
+	tests := []struct {
+		mode         string
+		hint         string
+		countries    []models.Country
+		country      string
+		localization string
+	}{
+		{LocalizationAuto, "", countries, "UA", models.LocalizationNationality},
+		{LocalizationAuto, "BY", countries, "BY", models.LocalizationHint},
+		{LocalizationAuto, "", []models.Country{{CountryId: "RU", Probability: 0.1}}, "", ""},
+		{LocalizationHint, "", countries, "", ""},
+		{LocalizationHint, "BY", countries, "BY", models.LocalizationHint},
+		{LocalizationOff, "BY", countries, "", ""},
+	}
+	for _, tt := range tests {
+		svc := &service{cfg: Config{Localization: tt.mode, CountryThreshold: 0.3}}
+		country, localization := svc.localization(tt.hint, tt.countries)
+		if country != tt.country || localization != tt.localization {
+			t.Errorf("%s with hint %q: expected %q/%q, got %q/%q", tt.mode, tt.hint, tt.country, tt.localization, country, localization)
+		}
+	}
+}
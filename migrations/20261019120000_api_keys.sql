@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "api_keys" (
+                          "id" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                          "name" varchar(256) NOT NULL,
+                          "key_hash" char(64) NOT NULL UNIQUE,
+                          "created_at" timestamptz NOT NULL DEFAULT now(),
+                          "revoked_at" timestamptz
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "api_keys";
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "api_keys" ADD COLUMN "roles" text[] NOT NULL DEFAULT '{reader}';
+ALTER TABLE "api_keys" ADD COLUMN "scopes" text[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "scopes";
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "roles";
+-- +goose StatementEnd
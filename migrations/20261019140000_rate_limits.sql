@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "rate_limits" (
+                             "key" varchar(256) NOT NULL,
+                             "window_start" timestamptz NOT NULL,
+                             "count" int NOT NULL,
+                             PRIMARY KEY ("key", "window_start")
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "rate_limits";
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+DELETE FROM "info" a USING "info" b WHERE a.person_id = b.person_id AND a.id < b.id;
+
+ALTER TABLE "info" ALTER COLUMN "person_id" TYPE BIGINT;
+ALTER TABLE "countries" ALTER COLUMN "person_id" TYPE BIGINT;
+
+ALTER TABLE "info" ADD CONSTRAINT "info_person_id_key" UNIQUE ("person_id");
+
+ALTER TABLE "info" DROP CONSTRAINT "info_person_id_fkey";
+ALTER TABLE "info" ADD CONSTRAINT "info_person_id_fkey"
+    FOREIGN KEY ("person_id") REFERENCES "people" ("id") ON DELETE CASCADE;
+ALTER TABLE "countries" DROP CONSTRAINT "countries_person_id_fkey";
+ALTER TABLE "countries" ADD CONSTRAINT "countries_person_id_fkey"
+    FOREIGN KEY ("person_id") REFERENCES "people" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "info" ADD CONSTRAINT "info_age_check" CHECK ("age" >= 0 AND "age" <= 150);
+ALTER TABLE "info" ADD CONSTRAINT "info_gender_probability_check" CHECK ("gender_probability" BETWEEN 0 AND 1);
+ALTER TABLE "countries" ADD CONSTRAINT "countries_probability_check" CHECK ("probability" BETWEEN 0 AND 1);
+
+CREATE INDEX "people_name_idx" ON "people" ("name");
+CREATE INDEX "people_surname_idx" ON "people" ("surname");
+CREATE INDEX "info_age_idx" ON "info" ("age");
+CREATE INDEX "info_gender_idx" ON "info" ("gender");
+CREATE INDEX "countries_person_id_idx" ON "countries" ("person_id");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "countries_person_id_idx";
+DROP INDEX IF EXISTS "info_gender_idx";
+DROP INDEX IF EXISTS "info_age_idx";
+DROP INDEX IF EXISTS "people_surname_idx";
+DROP INDEX IF EXISTS "people_name_idx";
+
+ALTER TABLE "countries" DROP CONSTRAINT IF EXISTS "countries_probability_check";
+ALTER TABLE "info" DROP CONSTRAINT IF EXISTS "info_gender_probability_check";
+ALTER TABLE "info" DROP CONSTRAINT IF EXISTS "info_age_check";
+
+ALTER TABLE "countries" DROP CONSTRAINT "countries_person_id_fkey";
+ALTER TABLE "countries" ADD CONSTRAINT "countries_person_id_fkey"
+    FOREIGN KEY ("person_id") REFERENCES "people" ("id");
+ALTER TABLE "info" DROP CONSTRAINT "info_person_id_fkey";
+ALTER TABLE "info" ADD CONSTRAINT "info_person_id_fkey"
+    FOREIGN KEY ("person_id") REFERENCES "people" ("id");
+
+ALTER TABLE "info" DROP CONSTRAINT IF EXISTS "info_person_id_key";
+
+ALTER TABLE "countries" ALTER COLUMN "person_id" TYPE int;
+ALTER TABLE "info" ALTER COLUMN "person_id" TYPE int;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "people" ADD COLUMN "created_at" timestamptz NOT NULL DEFAULT now();
+ALTER TABLE "people" ADD COLUMN "updated_at" timestamptz NOT NULL DEFAULT now();
+
+ALTER TABLE "info" ADD COLUMN "age_provider" varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE "info" ADD COLUMN "age_count" int NOT NULL DEFAULT 0;
+ALTER TABLE "info" ADD COLUMN "age_enriched_at" timestamptz;
+ALTER TABLE "info" ADD COLUMN "gender_provider" varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE "info" ADD COLUMN "gender_count" int NOT NULL DEFAULT 0;
+ALTER TABLE "info" ADD COLUMN "gender_enriched_at" timestamptz;
+ALTER TABLE "info" ADD COLUMN "nationality_provider" varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE "info" ADD COLUMN "nationality_count" int NOT NULL DEFAULT 0;
+ALTER TABLE "info" ADD COLUMN "nationality_enriched_at" timestamptz;
+
+-- existing rows were enriched by the only providers known before, the time is unknown
+UPDATE "info" SET "age_provider" = 'agify', "gender_provider" = 'genderize';
+UPDATE "info" i SET "nationality_provider" = 'nationalize'
+    WHERE EXISTS (SELECT 1 FROM "countries" c WHERE c.person_id = i.person_id);
+
+CREATE INDEX "info_enriched_at_idx" ON "info" (
+    LEAST(COALESCE("age_enriched_at", '-infinity'), COALESCE("gender_enriched_at", '-infinity'), COALESCE("nationality_enriched_at", '-infinity'))
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "info_enriched_at_idx";
+
+ALTER TABLE "info" DROP COLUMN IF EXISTS "nationality_enriched_at";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "nationality_count";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "nationality_provider";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "gender_enriched_at";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "gender_count";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "gender_provider";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "age_enriched_at";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "age_count";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "age_provider";
+
+ALTER TABLE "people" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "people" DROP COLUMN IF EXISTS "created_at";
+-- +goose StatementEnd
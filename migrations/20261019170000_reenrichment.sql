@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "info" ADD COLUMN "reenrich_lease_until" timestamptz;
+
+CREATE TABLE "enrichment_changes" (
+                          "id" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                          "person_id" BIGINT NOT NULL REFERENCES "people" ("id") ON DELETE CASCADE,
+                          "field" varchar(64) NOT NULL,
+                          "old_value" text NOT NULL,
+                          "new_value" text NOT NULL,
+                          "changed_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "enrichment_changes_person_id_idx" ON "enrichment_changes" ("person_id", "changed_at");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "enrichment_changes";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "reenrich_lease_until";
+-- +goose StatementEnd
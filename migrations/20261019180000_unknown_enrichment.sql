@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- unknown values used to be stored as zero values
+UPDATE "info" SET "age" = NULL WHERE "age" = 0;
+UPDATE "info" SET "gender" = NULL, "gender_probability" = NULL WHERE "gender" = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE "info" SET "age" = 0 WHERE "age" IS NULL;
+UPDATE "info" SET "gender" = '', "gender_probability" = 0 WHERE "gender" IS NULL;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "info" ADD COLUMN "age_country" varchar(2) NOT NULL DEFAULT '';
+ALTER TABLE "info" ADD COLUMN "gender_country" varchar(2) NOT NULL DEFAULT '';
+ALTER TABLE "info" ADD COLUMN "localization" varchar(16) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "info" DROP COLUMN IF EXISTS "localization";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "gender_country";
+ALTER TABLE "info" DROP COLUMN IF EXISTS "age_country";
+-- +goose StatementEnd
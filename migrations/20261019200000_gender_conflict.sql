@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "info" ADD COLUMN "gender_conflict" boolean NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "info" DROP COLUMN IF EXISTS "gender_conflict";
+-- +goose StatementEnd
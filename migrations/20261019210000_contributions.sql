@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "enrichment_contributions" (
+                          "id" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                          "person_id" BIGINT NOT NULL REFERENCES "people" ("id") ON DELETE CASCADE,
+                          "attribute" varchar(32) NOT NULL CHECK ("attribute" IN ('age', 'gender', 'nationality')),
+                          "provider" varchar(64) NOT NULL,
+                          "value" text NOT NULL,
+                          "probability" double precision CHECK ("probability" BETWEEN 0 AND 1),
+                          "weight" double precision NOT NULL,
+                          "count" integer NOT NULL DEFAULT 0,
+                          "country" varchar(2) NOT NULL DEFAULT '',
+                          "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "enrichment_contributions_person_id_idx" ON "enrichment_contributions" ("person_id");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "enrichment_contributions";
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "info" ADD COLUMN "reenriched_at" timestamptz;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "info" DROP COLUMN IF EXISTS "reenriched_at";
+-- +goose StatementEnd
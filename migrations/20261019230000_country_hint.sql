@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "info" ADD COLUMN "country_hint" varchar(2) NOT NULL DEFAULT '';
+-- hints used to be known only from the countries of localized requests
+UPDATE "info" SET "country_hint" = COALESCE(NULLIF("age_country", ''), "gender_country") WHERE "localization" = 'hint';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "info" DROP COLUMN IF EXISTS "country_hint";
+-- +goose StatementEnd
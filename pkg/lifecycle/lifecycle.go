@@ -0,0 +1,149 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nutochk/ef-test/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	ShutdownTimeout time.Duration `yaml:"SHUTDOWN_TIMEOUT" env:"SHUTDOWN_TIMEOUT" env-default:"30s"`
+	// HooksTimeout limits after-stop hooks separately, so they can flush even if stopping used up ShutdownTimeout
+	HooksTimeout time.Duration `yaml:"SHUTDOWN_HOOKS_TIMEOUT" env:"SHUTDOWN_HOOKS_TIMEOUT" env-default:"10s"`
+}
+
+// Lifecycle starts servers and background workers and stops them in order:
+// before-stop hooks (e.g. readiness drain), servers (in-flight requests are drained),
+// workers, and after-stop hooks in reverse order of registration (e.g. flushing logs, closing pools).
+type Lifecycle struct {
+	cfg        Config
+	logger     *logger.Logger
+	servers    []server
+	workers    []worker
+	beforeStop []hook
+	afterStop  []hook
+}
+
+type server struct {
+	name string
+	run  func() error
+	stop func(ctx context.Context) error
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func New(cfg Config, log *logger.Logger) *Lifecycle {
+	return &Lifecycle{cfg: cfg, logger: log}
+}
+
+// Serve registers a server: run blocks until the server is stopped by stop
+func (l *Lifecycle) Serve(name string, run func() error, stop func(ctx context.Context) error) {
+	l.servers = append(l.servers, server{name: name, run: run, stop: stop})
+}
+
+// Go registers a background worker, it must return when ctx is cancelled
+func (l *Lifecycle) Go(name string, run func(ctx context.Context) error) {
+	l.workers = append(l.workers, worker{name: name, run: run})
+}
+
+// BeforeStop registers a hook executed before servers are stopped
+func (l *Lifecycle) BeforeStop(name string, fn func(ctx context.Context) error) {
+	l.beforeStop = append(l.beforeStop, hook{name: name, fn: fn})
+}
+
+// AfterStop registers a hook executed after servers and workers are stopped, hooks run in reverse order
+func (l *Lifecycle) AfterStop(name string, fn func(ctx context.Context) error) {
+	l.afterStop = append(l.afterStop, hook{name: name, fn: fn})
+}
+
+// Run starts all components and blocks until ctx is cancelled or a component fails, then shuts everything down
+func (l *Lifecycle) Run(ctx context.Context) error {
+	failed := make(chan error, len(l.servers)+len(l.workers))
+
+	for _, s := range l.servers {
+		go func() {
+			l.logger.Info("starting server", zap.String("component", s.name))
+			if err := s.run(); err != nil {
+				failed <- fmt.Errorf("%s: %w", s.name, err)
+			}
+		}()
+	}
+
+	workersCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+	var wg sync.WaitGroup
+	for _, w := range l.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.logger.Info("starting worker", zap.String("component", w.name))
+			if err := w.run(workersCtx); err != nil && !errors.Is(err, context.Canceled) {
+				failed <- fmt.Errorf("%s: %w", w.name, err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		l.logger.Info("shutdown requested")
+	case runErr = <-failed:
+		l.logger.Error("component failed, shutting down", zap.Error(runErr))
+	}
+
+	// hooks before stop are not limited by the shutdown timeout, they usually wait on purpose
+	l.runHooks(context.Background(), l.beforeStop)
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), l.cfg.ShutdownTimeout)
+	defer cancel()
+
+	for _, s := range l.servers {
+		l.logger.Info("stopping server", zap.String("component", s.name))
+		if err := s.stop(stopCtx); err != nil {
+			l.logger.Error("failed to stop server gracefully", zap.String("component", s.name), zap.Error(err))
+		}
+	}
+
+	cancelWorkers()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		l.logger.Info("workers stopped")
+	case <-stopCtx.Done():
+		l.logger.Error("workers did not stop before shutdown timeout")
+	}
+
+	hooks := make([]hook, len(l.afterStop))
+	for i, h := range l.afterStop {
+		hooks[len(hooks)-1-i] = h
+	}
+	hooksCtx, cancelHooks := context.WithTimeout(context.Background(), l.cfg.HooksTimeout)
+	defer cancelHooks()
+	l.runHooks(hooksCtx, hooks)
+	return runErr
+}
+
+func (l *Lifecycle) runHooks(ctx context.Context, hooks []hook) {
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			l.logger.Error("shutdown hook failed", zap.String("component", h.name), zap.Error(err))
+		}
+	}
+}
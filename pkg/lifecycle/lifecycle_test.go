@@ -0,0 +1,88 @@
+package lifecycle
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nutochk/ef-test/pkg/logger"
+)
+
+func TestRunStopsInOrder(t *testing.T) {
+	log, _ := logger.New(logger.Config{Level: "error"})
+	l := New(Config{ShutdownTimeout: time.Second, HooksTimeout: time.Second}, log)
+
+	var mu sync.Mutex
+	var events []string
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+
+	stopped := make(chan struct{})
+	l.Serve("http", func() error {
+		<-stopped
+		return nil
+	}, func(context.Context) error {
+		record("server stopped")
+		close(stopped)
+		return nil
+	})
+	l.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		record("worker stopped")
+		return ctx.Err()
+	})
+	l.BeforeStop("drain", func(context.Context) error {
+		record("drained")
+		return nil
+	})
+	l.AfterStop("logger", func(context.Context) error {
+		record("logger synced")
+		return nil
+	})
+	l.AfterStop("postgres", func(context.Context) error {
+		record("pool closed")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"drained", "server stopped", "worker stopped", "pool closed", "logger synced"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v, got %v", expected, events)
+	}
+}
+
+func TestAfterStopHooksOutliveShutdownTimeout(t *testing.T) {
+	log, _ := logger.New(logger.Config{Level: "error"})
+	l := New(Config{ShutdownTimeout: 10 * time.Millisecond, HooksTimeout: time.Second}, log)
+
+	release := make(chan struct{})
+	defer close(release)
+	l.Go("stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+	var hookErr error
+	l.AfterStop("tracing", func(ctx context.Context) error {
+		hookErr = ctx.Err()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hookErr != nil {
+		t.Errorf("Expected live context in after-stop hook, got %v", hookErr)
+	}
+}
@@ -1,19 +1,48 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+type Config struct {
+	Level              string   `yaml:"LOG_LEVEL" env:"LOG_LEVEL" env-default:"info"`
+	Format             string   `yaml:"LOG_FORMAT" env:"LOG_FORMAT" env-default:"json"`
+	SamplingInitial    int      `yaml:"LOG_SAMPLING_INITIAL" env:"LOG_SAMPLING_INITIAL" env-default:"100"`
+	SamplingThereafter int      `yaml:"LOG_SAMPLING_THEREAFTER" env:"LOG_SAMPLING_THEREAFTER" env-default:"100"`
+	OutputPaths        []string `yaml:"LOG_OUTPUT" env:"LOG_OUTPUT" env-default:"stderr" env-separator:","`
+	RotateMaxSizeMB    int      `yaml:"LOG_ROTATE_MAX_SIZE_MB" env:"LOG_ROTATE_MAX_SIZE_MB" env-default:"100"`
+	RotateMaxBackups   int      `yaml:"LOG_ROTATE_MAX_BACKUPS" env:"LOG_ROTATE_MAX_BACKUPS" env-default:"5"`
+	RotateMaxAgeDays   int      `yaml:"LOG_ROTATE_MAX_AGE_DAYS" env:"LOG_ROTATE_MAX_AGE_DAYS" env-default:"30"`
+	RotateCompress     bool     `yaml:"LOG_ROTATE_COMPRESS" env:"LOG_ROTATE_COMPRESS" env-default:"false"`
+}
+
 type Logger struct {
 	logger *zap.Logger
+	level  zap.AtomicLevel
 }
 
+type contextKey struct{}
+
 func (l *Logger) Info(msg string, fields ...zap.Field) {
 	l.logger.Info(msg, fields...)
 }
 
+func (l *Logger) Warn(msg string, fields ...zap.Field) {
+	l.logger.Warn(msg, fields...)
+}
+
 func (l *Logger) Fatal(msg string, fields ...zap.Field) {
 	l.logger.Fatal(msg, fields...)
 }
@@ -26,13 +55,99 @@ func (l *Logger) Debug(msg string, fields ...zap.Field) {
 	l.logger.Debug(msg, fields...)
 }
 
-func New() (*Logger, error) {
-	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zap.DebugLevel) // Включаем Debug
+// Sync flushes buffered entries
+func (l *Logger) Sync() error {
+	return l.logger.Sync()
+}
+
+// Level returns current minimal level of entries
+func (l *Logger) Level() string {
+	return l.level.String()
+}
+
+// SetLevel changes minimal level of entries at runtime for the logger and all its children
+func (l *Logger) SetLevel(level string) error {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level.SetLevel(lvl)
+	return nil
+}
+
+// With creates a child logger that adds fields to every entry
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	return &Logger{logger: l.logger.With(fields...), level: l.level}
+}
+
+// WithContext returns a copy of ctx carrying l
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns logger carried by ctx or fallback if there is none
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback
+}
 
-	l, err := config.Build()
+func New(cfg Config) (*Logger, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create logger: %w", err)
+		return nil, fmt.Errorf("failed to parse log level: %w", err)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case FormatJSON, "":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case FormatConsole:
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", cfg.Format)
+	}
+
+	paths := cfg.OutputPaths
+	if len(paths) == 0 {
+		paths = []string{"stderr"}
+	}
+	sinks := make([]zapcore.WriteSyncer, 0, len(paths))
+	for _, path := range paths {
+		sinks = append(sinks, sink(path, cfg))
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(sinks...), level)
+	if cfg.SamplingInitial > 0 && cfg.SamplingThereafter > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.SamplingInitial, cfg.SamplingThereafter)
+	}
+
+	l := zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
+	return &Logger{logger: l, level: level}, nil
+}
+
+// sink opens output path, files are rotated by size
+func sink(path string, cfg Config) zapcore.WriteSyncer {
+	switch path {
+	case "stdout":
+		return zapcore.Lock(os.Stdout)
+	case "stderr":
+		return zapcore.Lock(os.Stderr)
 	}
-	return &Logger{logger: l}, nil
+	return zapcore.AddSync(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    cfg.RotateMaxSizeMB,
+		MaxBackups: cfg.RotateMaxBackups,
+		MaxAge:     cfg.RotateMaxAgeDays,
+		Compress:   cfg.RotateCompress,
+	})
 }
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Migrator applies goose migrations from fsys, concurrent runs from several instances are serialized by advisory lock
+type Migrator struct {
+	pool     *pgxpool.Pool
+	provider *goose.Provider
+	target   int64
+}
+
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration lock: %w", err)
+	}
+	db := stdlib.OpenDBFromPool(pool)
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys, goose.WithSessionLocker(locker))
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	m := &Migrator{pool: pool, provider: provider}
+	if sources := provider.ListSources(); len(sources) > 0 {
+		m.target = sources[len(sources)-1].Version
+	}
+	return m, nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the latest applied migration
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Redo rolls back the latest applied migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// To migrates up or down to the given version
+func (m *Migrator) To(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	current, err := m.provider.GetDBVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version >= current {
+		return m.provider.UpTo(ctx, version)
+	}
+	return m.provider.DownTo(ctx, version)
+}
+
+// Status returns state of every known migration
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// Close releases database handle, the pool stays open
+func (m *Migrator) Close() error {
+	return m.provider.Close()
+}
+
+// Check returns error if database schema is behind the latest known migration. It only reads version table,
+// so it is cheap enough for readiness probes
+func (m *Migrator) Check(ctx context.Context) error {
+	var current int64
+	err := m.pool.QueryRow(ctx, `SELECT COALESCE(max(version_id), 0) FROM goose_db_version WHERE is_applied`).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to get database version: %w", err)
+	}
+	if current < m.target {
+		return fmt.Errorf("database version %d is behind migrations version %d", current, m.target)
+	}
+	return nil
+}
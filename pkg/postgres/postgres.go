@@ -2,23 +2,23 @@ package postgres
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/stdlib"
-	"github.com/pressly/goose/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Config struct {
-	Host     string `yaml:"POSTGRES_HOST" env:"POSTGRES_HOST" `
-	Port     int    `yaml:"POSTGRES_PORT" env:"POSTGRES_PORT" `
-	User     string `yaml:"POSTGRES_USER" env:"POSTGRES_USER" `
+	Host     string `yaml:"POSTGRES_HOST" env:"POSTGRES_HOST" env-required:"true"`
+	Port     int    `yaml:"POSTGRES_PORT" env:"POSTGRES_PORT" env-default:"5432"`
+	User     string `yaml:"POSTGRES_USER" env:"POSTGRES_USER" env-required:"true"`
 	Password string `yaml:"POSTGRES_PASSWORD" env:"POSTGRES_PASSWORD"`
-	Database string `yaml:"POSTGRES_DB" env:"POSTGRES_DB"`
+	Database string `yaml:"POSTGRES_DB" env:"POSTGRES_DB" env-required:"true"`
+	MaxConns int32  `yaml:"POSTGRES_MAX_CONNS" env:"POSTGRES_MAX_CONNS"`
+	// SkipMigrations disables applying migrations on server start, they are applied with migrate command then
+	SkipMigrations bool `yaml:"POSTGRES_SKIP_MIGRATIONS" env:"POSTGRES_SKIP_MIGRATIONS" env-default:"false"`
 }
 
-func New(cfg Config) (*pgx.Conn, error) {
+func New(cfg Config) (*pgxpool.Pool, error) {
 	connString := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		cfg.User,
 		cfg.Password,
@@ -26,21 +26,21 @@ func New(cfg Config) (*pgx.Conn, error) {
 		cfg.Port,
 		cfg.Database,
 	)
-	conn, err := pgx.Connect(context.Background(), connString)
+	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
-
-	migrationsDir := "./migrations"
-	connConfig, err := pgx.ParseConfig(connString)
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
-	sqlDB := stdlib.OpenDB(*connConfig)
-	if err = goose.Up(sqlDB, migrationsDir); err != nil {
-		if !errors.Is(err, goose.ErrNoMigrations) {
-			return nil, fmt.Errorf("failed to apply migrations: %w", err)
-		}
+	if err = pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
-	return conn, nil
+	return pool, nil
 }